@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"torrent/pkg/bencoder"
+	"torrent/pkg/torrent"
+)
+
+// stringList is a flag that may be repeated, collecting every value.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// createCommand writes a torrent of a file or directory.
+func createCommand(args []string) error {
+	const usage = "create [-o output] [-t tracker[,tracker]]... [-w webseed]... [-c comment] [-private] [-source source] [-piece-length bytes] [-align] <path>"
+	var trackers, webSeeds stringList
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	flags.Var(&trackers, "t", "add a tier of comma-separated tracker URLs")
+	flags.Var(&webSeeds, "w", "add a web seed URL")
+	output := flags.String("o", "", "write to this file instead of <name>.torrent")
+	comment := flags.String("c", "", "set the comment")
+	createdBy := flags.String("created-by", "go-torrent", "set the creating program")
+	private := flags.Bool("private", false, "mark the torrent private")
+	source := flags.String("source", "", "set the source tag")
+	pieceLength := flags.Int64("piece-length", 0, "set the piece length, chosen from the content size when 0")
+	align := flags.Bool("align", false, "pad files so that each starts on a piece boundary")
+	quiet := flags.Bool("q", false, "do not report progress")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError(usage)
+	}
+
+	builder := torrent.Builder{
+		WebSeeds:    webSeeds,
+		Comment:     *comment,
+		CreatedBy:   *createdBy,
+		Private:     *private,
+		Source:      *source,
+		PieceLength: *pieceLength,
+		Align:       *align,
+	}
+	for _, tier := range trackers {
+		builder.Trackers = append(builder.Trackers, strings.Split(tier, ","))
+	}
+	if !*quiet {
+		builder.Progress = progressPrinter("hashing")
+	}
+
+	path := flags.Arg(0)
+	torrentFile, err := builder.Build(path)
+	if err != nil {
+		return err
+	}
+	data, err := bencoder.NewSimpleBencoder().Marshal(torrentFile)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		// the builder names the torrent after the resolved path, so "."
+		// gets the name of the current directory
+		*output = torrentFile.Info.Name + ".torrent"
+	}
+	return writeOutput(*output, data)
+}
+
+// progressPrinter returns a progress callback printing the percentage done
+// to standard error whenever it changes.
+func progressPrinter(label string) func(done, total int64) {
+	last := -1
+	return func(done, total int64) {
+		percent := 100
+		if total > 0 {
+			percent = int(done * 100 / total)
+		}
+		if percent == last {
+			return
+		}
+		last = percent
+		fmt.Fprintf(os.Stderr, "\r%s: %3d%%", label, percent)
+		if done == total {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
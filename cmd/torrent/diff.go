@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"torrent/pkg/bencoder"
+)
+
+// diffCommand prints the structural differences between two bencoded files.
+func diffCommand(args []string) error {
+	const usage = "diff <file|-> <file|->"
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return usageError(usage)
+	}
+
+	a, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := readInput(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	changes, err := bencoder.Diff(a, b)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"torrent/pkg/bencoder"
+)
+
+// dumpCommand prints a bencoded file as JSON.
+func dumpCommand(args []string) error {
+	const usage = "dump [-base64] [-compact] [-o output] <file|->"
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	useBase64 := flags.Bool("base64", false, "write binary strings as base64 instead of hex")
+	compact := flags.Bool("compact", false, "do not indent the JSON")
+	output := flags.String("o", "", "write to this file instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError(usage)
+	}
+
+	data, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	binary := bencoder.BinaryHex
+	if *useBase64 {
+		binary = bencoder.BinaryBase64
+	}
+	converted, err := bencoder.ToJSON(data, binary)
+	if err != nil {
+		return err
+	}
+
+	if !*compact {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, converted, "", "  "); err != nil {
+			return err
+		}
+		converted = indented.Bytes()
+	}
+	return writeOutput(*output, append(converted, '\n'))
+}
+
+// fromJSONCommand converts JSON written by dump back to bencode.
+func fromJSONCommand(args []string) error {
+	const usage = "from-json [-o output] <file|->"
+	flags := flag.NewFlagSet("from-json", flag.ContinueOnError)
+	output := flags.String("o", "", "write to this file instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError(usage)
+	}
+
+	data, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	encoded, err := bencoder.FromJSON(data)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, encoded)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"torrent/pkg/torrent"
+)
+
+// magnetCommand prints the magnet link of a torrent file.
+func magnetCommand(args []string) error {
+	const usage = "magnet <file|->"
+	flags := flag.NewFlagSet("magnet", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError(usage)
+	}
+
+	data, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	torrentFile, err := torrent.NewTorrentFromBencode(data)
+	if err != nil {
+		return err
+	}
+	magnet, err := torrentFile.Magnet()
+	if err != nil {
+		return err
+	}
+	fmt.Println(magnet)
+	return nil
+}
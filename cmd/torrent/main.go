@@ -1,11 +1,37 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"sort"
+	"strings"
 	"torrent/config"
 )
 
+// commands are the subcommands of the torrent tool, run as
+// "torrent <command> [arguments]".
+var commands = map[string]func(args []string) error{
+	"create":    createCommand,
+	"diff":      diffCommand,
+	"dump":      dumpCommand,
+	"from-json": fromJSONCommand,
+	"magnet":    magnetCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			log.Fatalf("unknown command %q, expected one of: %s", os.Args[1], commandNames())
+		}
+		if err := command(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	var err error
 	settings, err := config.LoadConfig(".")
 	if err != nil {
@@ -13,3 +39,34 @@ func main() {
 	}
 	log.Print(settings)
 }
+
+func commandNames() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// readInput reads the named file, or standard input for "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// writeOutput writes data to the named file, or standard output for "" or "-".
+func writeOutput(name string, data []byte) error {
+	if name == "" || name == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
+
+// usageError reports wrong arguments along with the command's usage line.
+func usageError(usage string) error {
+	return fmt.Errorf("usage: torrent %s", usage)
+}
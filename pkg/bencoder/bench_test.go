@@ -0,0 +1,137 @@
+package bencoder
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+type benchFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type benchInfo struct {
+	Files       []benchFile `bencode:"files"`
+	Name        string      `bencode:"name"`
+	PieceLength int64       `bencode:"piece length"`
+	Pieces      []byte      `bencode:"pieces"`
+	Private     int64       `bencode:"private"`
+}
+
+type benchTorrent struct {
+	Announce     string     `bencode:"announce"`
+	AnnounceList [][]string `bencode:"announce-list"`
+	Comment      string     `bencode:"comment"`
+	CreatedBy    string     `bencode:"created by"`
+	CreationDate int64      `bencode:"creation date"`
+	Info         benchInfo  `bencode:"info"`
+}
+
+func newBenchTorrent() benchTorrent {
+	files := make([]benchFile, 1000)
+	for i := range files {
+		files[i] = benchFile{Length: int64(i) * 1024, Path: []string{"directory", fmt.Sprintf("file-%04d.dat", i)}}
+	}
+	return benchTorrent{
+		Announce:     "udp://tracker.example.com:80/announce",
+		AnnounceList: [][]string{{"udp://tracker.example.com:80/announce"}, {"udp://backup.example.com:1337/announce"}},
+		Comment:      "benchmark",
+		CreatedBy:    "go-torrent",
+		CreationDate: 1744127373,
+		Info: benchInfo{
+			Files:       files,
+			Name:        "bench",
+			PieceLength: 262144,
+			Pieces:      bytes.Repeat([]byte{0xab}, 20*4000),
+			Private:     1,
+		},
+	}
+}
+
+func benchTorrentData(b *testing.B) []byte {
+	data, err := Marshal(newBenchTorrent())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func BenchmarkUnmarshalStruct(b *testing.B) {
+	data := benchTorrentData(b)
+	bencoder := NewSimpleBencoder()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var torrent benchTorrent
+		if err := bencoder.Unmarshal(data, &torrent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalSkipUnknown(b *testing.B) {
+	data := benchTorrentData(b)
+	bencoder := NewSimpleBencoder()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var torrent struct {
+			Announce string `bencode:"announce"`
+		}
+		if err := bencoder.Unmarshal(data, &torrent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeTree(b *testing.B) {
+	data := benchTorrentData(b)
+	bencoder := NewSimpleBencoder()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := bencoder.Decode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalStruct(b *testing.B) {
+	torrent := newBenchTorrent()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(torrent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeTree(b *testing.B) {
+	tree, err := NewSimpleBencoder().Decode(benchTorrentData(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(tree); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	data := benchTorrentData(b)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Get(data, "info", "piece length"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -12,23 +12,33 @@ type Bencoder interface {
 	Marshal(target interface{}) ([]byte, error)
 }
 
-type SimpleBencoder struct{}
+// SimpleBencoder decodes and encodes whole documents. Like a Decoder it is
+// lenient unless DisallowNonCanonical is called: non-canonical input, data
+// after the top-level value included, is accepted.
+type SimpleBencoder struct {
+	strict bool
+}
 
 func NewSimpleBencoder() *SimpleBencoder {
 	return &SimpleBencoder{}
 }
 
+// DisallowNonCanonical makes Decode and Unmarshal fail with a
+// CanonicalError on input that is not canonical, such as trailing data.
+func (bencoder *SimpleBencoder) DisallowNonCanonical() {
+	bencoder.strict = true
+}
+
 func (bencoder *SimpleBencoder) Decode(data []byte) (interface{}, error) {
-	if len(data) == 0 {
-		return nil, errors.New("empty data")
+	var value interface{}
+	if _, err := decodeBytes(data, &value, bencoder.strict); err != nil {
+		return nil, err
 	}
-
-	decodeFunc := getDecoder(data)
-	return decodeFunc(data)
+	return value, nil
 }
 
 func (bencoder *SimpleBencoder) Encode(data interface{}) ([]byte, error) {
-	return encode(data)
+	return Marshal(data)
 }
 
 func (bencoder *SimpleBencoder) Unmarshal(data []byte, target interface{}) error {
@@ -36,30 +46,18 @@ func (bencoder *SimpleBencoder) Unmarshal(data []byte, target interface{}) error
 		return errors.New("struct type required for writing")
 	}
 
-	decodedData, err := bencoder.Decode(data)
-	if err != nil {
-		return err
-	}
-
-	mappedData, ok := decodedData.(map[string]interface{})
-	if !ok {
-		return errors.New("failed to cast decoded data to map")
-	}
-
-	for key, value := range mappedData {
-		if err := setField(target, key, value); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	_, err := decodeBytes(data, target, bencoder.strict)
+	return err
 }
 
 func (bencoder *SimpleBencoder) Marshal(target interface{}) ([]byte, error) {
-	mappedData, err := mapStructToMap(target)
-	if err != nil {
-		return nil, err
+	reflectValue := reflect.ValueOf(target)
+	if reflectValue.Kind() == reflect.Ptr {
+		reflectValue = reflectValue.Elem()
+	}
+	if reflectValue.Kind() != reflect.Struct {
+		return nil, errors.New("struct or pointer to struct required")
 	}
 
-	return bencoder.Encode(mappedData)
+	return Marshal(target)
 }
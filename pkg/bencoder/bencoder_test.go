@@ -2,7 +2,6 @@ package bencoder
 
 import (
 	"errors"
-	"fmt"
 	"reflect"
 	"testing"
 )
@@ -33,7 +32,7 @@ func TestSimpleBencoder_Decode(t *testing.T) {
 			name:    "Integer Decode Error",
 			args:    args{data: []byte("i128")},
 			want:    nil,
-			wantErr: fmt.Errorf("invalid integer format: %s", string([]byte("i128"))),
+			wantErr: &SyntaxError{Offset: 4, Expected: "'e'", msg: "unexpected end of input"},
 		},
 		{
 			name:    "String Decode",
@@ -42,28 +41,28 @@ func TestSimpleBencoder_Decode(t *testing.T) {
 			wantErr: nil,
 		},
 		{
-			name:    "String Decode Fail On Longer",
+			name:    "String Decode Ignores Trailing Data",
 			args:    args{data: []byte("3:spam")},
-			want:    nil,
-			wantErr: fmt.Errorf("mismatch of length and byte string"),
+			want:    []byte("spa"),
+			wantErr: nil,
 		},
 		{
 			name:    "String Decode Fail On Shorter",
 			args:    args{data: []byte("5:spam")},
 			want:    nil,
-			wantErr: fmt.Errorf("mismatch of length and byte string"),
+			wantErr: &SyntaxError{Offset: 6, Expected: "string of length 5", msg: "unexpected end of input"},
 		},
 		{
 			name:    "String Decode no length",
 			args:    args{data: []byte("spam")},
 			want:    nil,
-			wantErr: fmt.Errorf("unknown format"),
+			wantErr: &SyntaxError{Offset: 0, Expected: "value", msg: "invalid character 's'"},
 		},
 		{
 			name:    "String Decode Fake Length",
 			args:    args{data: []byte("s:spam")},
 			want:    nil,
-			wantErr: fmt.Errorf("length of string is not correct"),
+			wantErr: &SyntaxError{Offset: 0, Expected: "value", msg: "invalid character 's'"},
 		},
 		// List decoding test cases
 		{
@@ -97,19 +96,19 @@ func TestSimpleBencoder_Decode(t *testing.T) {
 			name:    "List Decode with Invalid Format",
 			args:    args{data: []byte("l4:spam4:eggsi2e")},
 			want:    nil,
-			wantErr: fmt.Errorf("list element format invalid"),
+			wantErr: &SyntaxError{Offset: 16, Expected: "list element or 'e'", msg: "unexpected end of input"},
 		},
 		{
 			name:    "List Decode with Invalid elements",
 			args:    args{data: []byte("l4:spa4:eggsi2e")},
 			want:    nil,
-			wantErr: fmt.Errorf("list element format invalid"),
+			wantErr: &SyntaxError{Offset: 7, Expected: "value", msg: "invalid character ':'"},
 		},
 		{
 			name:    "List Decode with Invalid elements",
 			args:    args{data: []byte("l4:spammmm4:spame")},
 			want:    nil,
-			wantErr: fmt.Errorf("list element format invalid"),
+			wantErr: &SyntaxError{Offset: 7, Expected: "value", msg: "invalid character 'm'"},
 		},
 		// List with Dictionary inside
 		{
@@ -128,19 +127,19 @@ func TestSimpleBencoder_Decode(t *testing.T) {
 			name:    "List with Dict Decode with Invalid Key Format",
 			args:    args{data: []byte("ld3:bar4:spami42ee")},
 			want:    nil,
-			wantErr: fmt.Errorf("list element format invalid"),
+			wantErr: &SyntaxError{Offset: 13, Expected: "string key", msg: "invalid dictionary key 'i'"},
 		},
 		{
 			name:    "List with Dict Decode with Invalid Value Format",
 			args:    args{data: []byte("ld3:bar4:spami42e")},
 			want:    nil,
-			wantErr: fmt.Errorf("list element format invalid"),
+			wantErr: &SyntaxError{Offset: 13, Expected: "string key", msg: "invalid dictionary key 'i'"},
 		},
 		{
 			name:    "List with Dict Decode with Unsorted Keys",
 			args:    args{data: []byte("ld3:foo4:spam3:bar4:eggs3:foo4:testee")},
 			want:    nil,
-			wantErr: fmt.Errorf("list element format invalid"),
+			wantErr: &SyntaxError{Offset: 24, Expected: "unique key", msg: "duplicate dictionary key \"foo\""},
 		},
 		// Dictionary decoding test cases
 		{
@@ -165,19 +164,19 @@ func TestSimpleBencoder_Decode(t *testing.T) {
 			name:    "Dict Decode with Invalid Key Format",
 			args:    args{data: []byte("d3:bar4:spami42ee")},
 			want:    nil,
-			wantErr: fmt.Errorf("invalid dictionary format"),
+			wantErr: &SyntaxError{Offset: 12, Expected: "string key", msg: "invalid dictionary key 'i'"},
 		},
 		{
 			name:    "Dict Decode with Invalid Value Format",
 			args:    args{data: []byte("d3:bar4:spami42")},
 			want:    nil,
-			wantErr: fmt.Errorf("invalid dictionary format"),
+			wantErr: &SyntaxError{Offset: 12, Expected: "string key", msg: "invalid dictionary key 'i'"},
 		},
 		{
 			name:    "Dict Decode with repeated Keys",
 			args:    args{data: []byte("d3:foo4:spam3:bar4:eggs3:foo4:teste")},
 			want:    nil,
-			wantErr: fmt.Errorf("invalid dictionary format"),
+			wantErr: &SyntaxError{Offset: 23, Expected: "unique key", msg: "duplicate dictionary key \"foo\""},
 		},
 		{
 			name:    "Dict with List Decode",
@@ -189,7 +188,7 @@ func TestSimpleBencoder_Decode(t *testing.T) {
 			name:    "Dict with List Decode Invalid End",
 			args:    args{data: []byte("d3:barli1ei2ei3ee3:foo4:spam4:spaml4:eggse")},
 			want:    nil,
-			wantErr: fmt.Errorf("invalid dictionary format"),
+			wantErr: &SyntaxError{Offset: 42, Expected: "dictionary key or 'e'", msg: "unexpected end of input"},
 		},
 		{
 			name:    "Dict with Nested List Decode",
@@ -201,13 +200,13 @@ func TestSimpleBencoder_Decode(t *testing.T) {
 			name:    "Dict with List Decode with Invalid Format",
 			args:    args{data: []byte("d3:barli1ei2ei3e3:foo4:spam3:spaml4:eggse")},
 			want:    nil,
-			wantErr: fmt.Errorf("invalid dictionary format"),
+			wantErr: &SyntaxError{Offset: 32, Expected: "value", msg: "invalid character 'm'"},
 		},
 		{
 			name:    "Dict with List Decode with Invalid Elements",
 			args:    args{data: []byte("d3:barli1e4:eggs3:foo4:spam3:spaml4:eggse")},
 			want:    nil,
-			wantErr: fmt.Errorf("invalid dictionary format"),
+			wantErr: &SyntaxError{Offset: 32, Expected: "value", msg: "invalid character 'm'"},
 		},
 		{
 			name: "Full Torrent File Decode",
@@ -507,6 +506,34 @@ func TestSimpleBencoder_Unmarshal(t *testing.T) {
 	}
 }
 
+func TestSimpleBencoder_TrailingData(t *testing.T) {
+	data := []byte("d3:fooi1ee3:bar")
+	var target struct {
+		Foo int64 `bencode:"foo"`
+	}
+
+	lenient := NewSimpleBencoder()
+	if value, err := lenient.Decode(data); err != nil || !reflect.DeepEqual(value, map[string]interface{}{"foo": int64(1)}) {
+		t.Errorf("Decode() = %v, %v", value, err)
+	}
+	if err := lenient.Unmarshal(data, &target); err != nil || target.Foo != 1 {
+		t.Errorf("Unmarshal() = %+v, %v", target, err)
+	}
+
+	strict := NewSimpleBencoder()
+	strict.DisallowNonCanonical()
+	want := &CanonicalError{Offset: 10, Err: ErrTrailingData}
+	if _, err := strict.Decode(data); !reflect.DeepEqual(err, want) {
+		t.Errorf("Decode() error = %v, want %v", err, want)
+	}
+	if err := strict.Unmarshal(data, &target); !reflect.DeepEqual(err, want) {
+		t.Errorf("Unmarshal() error = %v, want %v", err, want)
+	}
+	if _, err := strict.Decode([]byte("d3:fooi1ee")); err != nil {
+		t.Errorf("Decode() of a canonical document error = %v", err)
+	}
+}
+
 func TestSimpleBencoder_Marshal(t *testing.T) {
 	type args struct {
 		target interface{}
@@ -0,0 +1,106 @@
+package bencoder
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// encoderFunc writes value, whose type it was built for, to w.
+type encoderFunc func(w tokenWriter, value reflect.Value) error
+
+// decoderFunc decodes the value starting with b into target, whose type it
+// was built for.
+type decoderFunc func(d *Decoder, b byte, target reflect.Value) error
+
+// structInfo is the field table of a struct type: its dict keys in sorted
+// order, the index of each key's field and the field tagged extra, if any.
+type structInfo struct {
+	fields   []structField
+	byName   map[string]int
+	extra    *structField
+	extraErr error
+}
+
+var extraMapType = reflect.TypeOf(map[string]RawMessage(nil))
+
+var (
+	structCache  sync.Map // map[reflect.Type]*structInfo
+	encoderCache sync.Map // map[reflect.Type]encoderFunc
+	decoderCache sync.Map // map[reflect.Type]decoderFunc
+)
+
+// cachedStructInfo returns the field table of the struct type t, building
+// it on first use.
+func cachedStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info := &structInfo{byName: map[string]int{}}
+	for _, field := range structFields(t) {
+		if field.extra {
+			field := field
+			info.extra = &field
+			continue
+		}
+		info.byName[field.name] = len(info.fields)
+		info.fields = append(info.fields, field)
+	}
+	if info.extra != nil && !info.extra.typ.ConvertibleTo(extraMapType) {
+		info.extraErr = fmt.Errorf("extra field of %s must be a map[string]bencoder.RawMessage, not %s", t, info.extra.typ)
+	}
+	cached, _ := structCache.LoadOrStore(t, info)
+	return cached.(*structInfo)
+}
+
+// typeEncoder returns the encoder for t, building it on first use.
+func typeEncoder(t reflect.Type) encoderFunc {
+	if f, ok := encoderCache.Load(t); ok {
+		return f.(encoderFunc)
+	}
+
+	// A recursive type reaches itself while its encoder is being built, so
+	// store a stand-in first that waits for the real encoder.
+	var (
+		wg sync.WaitGroup
+		f  encoderFunc
+	)
+	wg.Add(1)
+	stored, loaded := encoderCache.LoadOrStore(t, encoderFunc(func(w tokenWriter, value reflect.Value) error {
+		wg.Wait()
+		return f(w, value)
+	}))
+	if loaded {
+		return stored.(encoderFunc)
+	}
+
+	f = newTypeEncoder(t, true)
+	wg.Done()
+	encoderCache.Store(t, f)
+	return f
+}
+
+// typeDecoder returns the decoder for t, building it on first use.
+func typeDecoder(t reflect.Type) decoderFunc {
+	if f, ok := decoderCache.Load(t); ok {
+		return f.(decoderFunc)
+	}
+
+	var (
+		wg sync.WaitGroup
+		f  decoderFunc
+	)
+	wg.Add(1)
+	stored, loaded := decoderCache.LoadOrStore(t, decoderFunc(func(d *Decoder, b byte, target reflect.Value) error {
+		wg.Wait()
+		return f(d, b, target)
+	}))
+	if loaded {
+		return stored.(decoderFunc)
+	}
+
+	f = newTypeDecoder(t)
+	wg.Done()
+	decoderCache.Store(t, f)
+	return f
+}
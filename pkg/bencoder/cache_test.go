@@ -0,0 +1,88 @@
+package bencoder
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type treeNode struct {
+	Name     string      `bencode:"name"`
+	Children []*treeNode `bencode:"children,omitempty"`
+	Parent   *treeNode   `bencode:"parent"`
+}
+
+func TestCodecs_RecursiveType(t *testing.T) {
+	want := treeNode{
+		Name: "root",
+		Children: []*treeNode{
+			{Name: "a", Children: []*treeNode{{Name: "a1"}}},
+			{Name: "b"},
+		},
+	}
+
+	data, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != "d8:childrenld8:childrenld4:name2:a1ee4:name1:aed4:name1:bee4:name4:roote" {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	var got treeNode
+	if err := NewSimpleBencoder().Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCodecs_ConcurrentFirstUse(t *testing.T) {
+	type message struct {
+		ID    int               `bencode:"id"`
+		Tags  []string          `bencode:"tags"`
+		Extra map[string]uint16 `bencode:"extra"`
+	}
+	want := message{ID: 7, Tags: []string{"x", "y"}, Extra: map[string]uint16{"port": 6881}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := Marshal(want)
+			if err != nil {
+				errs <- err
+				return
+			}
+			var got message
+			if err := NewSimpleBencoder().Unmarshal(data, &got); err != nil {
+				errs <- err
+				return
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip = %+v, want %+v", got, want)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestUnmarshal_ReusesSlice(t *testing.T) {
+	target := struct {
+		Values []int `bencode:"values"`
+	}{Values: []int{9, 9, 9, 9}}
+
+	if err := NewSimpleBencoder().Unmarshal([]byte("d6:valuesli1ei2eee"), &target); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(target.Values, []int{1, 2}) {
+		t.Errorf("Values = %v, want [1 2]", target.Values)
+	}
+}
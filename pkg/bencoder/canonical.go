@@ -0,0 +1,121 @@
+package bencoder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Reasons a document decodes but is not in the canonical form BEP 3
+// requires. They are wrapped in a CanonicalError.
+var (
+	ErrUnsortedKeys      = errors.New("dictionary keys not sorted")
+	ErrNegativeZero      = errors.New("negative zero integer")
+	ErrLeadingZero       = errors.New("leading zero in integer")
+	ErrLeadingZeroLength = errors.New("leading zero in string length")
+	ErrTrailingData      = errors.New("trailing data after top-level value")
+)
+
+// CanonicalError reports non-canonical input and where it was found. In
+// strict mode it is returned as the decode error; otherwise it is collected
+// as a warning and decoding carries on.
+type CanonicalError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *CanonicalError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Err, e.Offset)
+}
+
+func (e *CanonicalError) Unwrap() error {
+	return e.Err
+}
+
+// DisallowNonCanonical makes the Decoder fail on input that is valid but
+// not canonical, instead of recording it in Warnings.
+func (d *Decoder) DisallowNonCanonical() {
+	d.strict = true
+}
+
+// Warnings returns the non-canonical input found by the last Decode call.
+// Each Decode starts a new list, so a long-lived Decoder does not keep the
+// warnings of every value it has read.
+func (d *Decoder) Warnings() []*CanonicalError {
+	return d.warnings
+}
+
+// nonCanonical fails in strict mode and records a warning otherwise.
+func (d *Decoder) nonCanonical(offset int64, reason error) error {
+	err := &CanonicalError{Offset: offset, Err: reason}
+	if d.strict {
+		return err
+	}
+	d.warnings = append(d.warnings, err)
+	return nil
+}
+
+// checkInteger checks the digits of an integer starting at offset.
+func (d *Decoder) checkInteger(offset int64, digits []byte) error {
+	switch {
+	case string(digits) == "-0":
+		return d.nonCanonical(offset, ErrNegativeZero)
+	case len(digits) > 1 && (digits[0] == '0' || bytes.HasPrefix(digits, []byte("-0"))):
+		return d.nonCanonical(offset, ErrLeadingZero)
+	}
+	return nil
+}
+
+// checkLength checks the length prefix of a string starting at offset.
+func (d *Decoder) checkLength(offset int64, digits []byte) error {
+	if len(digits) > 1 && digits[0] == '0' {
+		return d.nonCanonical(offset, ErrLeadingZeroLength)
+	}
+	return nil
+}
+
+// checkKeyOrder checks key, starting at offset, sorts after the previous key.
+func (d *Decoder) checkKeyOrder(offset int64, previous []byte, key []byte) error {
+	if previous != nil && bytes.Compare(previous, key) > 0 {
+		return d.nonCanonical(offset, ErrUnsortedKeys)
+	}
+	return nil
+}
+
+// DecodeStrict decodes data into v, rejecting anything BEP 3 does not
+// allow: unsorted keys, negative zero, leading zeros and trailing data.
+func DecodeStrict(data []byte, v interface{}) error {
+	_, err := decodeBytes(data, v, true)
+	return err
+}
+
+// DecodeLenient decodes data into v, accepting non-canonical input and
+// returning a warning for each problem found.
+func DecodeLenient(data []byte, v interface{}) ([]*CanonicalError, error) {
+	return decodeBytes(data, v, false)
+}
+
+func decodeBytes(data []byte, v interface{}, strict bool) ([]*CanonicalError, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty data")
+	}
+
+	decoder := NewDecoder(bytes.NewReader(data))
+	decoder.strict = strict
+	if err := decoder.Decode(v); err != nil {
+		return decoder.Warnings(), err
+	}
+	if err := decoder.checkTrailing(int64(len(data))); err != nil {
+		return decoder.Warnings(), err
+	}
+	return decoder.Warnings(), nil
+}
+
+// checkTrailing checks that the top-level value just decoded ends a
+// document of size bytes.
+func (d *Decoder) checkTrailing(size int64) error {
+	if d.offset != size {
+		return d.nonCanonical(d.offset, ErrTrailingData)
+	}
+	return nil
+}
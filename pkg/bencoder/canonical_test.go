@@ -0,0 +1,112 @@
+package bencoder
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantErr    error
+		wantOffset int64
+	}{
+		{name: "Canonical", data: "d3:bari1e3:fool3:bazi-5eee"},
+		{name: "Unsorted Keys", data: "d3:fooi1e3:bari2ee", wantErr: ErrUnsortedKeys, wantOffset: 9},
+		{name: "Negative Zero", data: "li-0ee", wantErr: ErrNegativeZero, wantOffset: 2},
+		{name: "Leading Zero", data: "i03e", wantErr: ErrLeadingZero, wantOffset: 1},
+		{name: "Negative Leading Zero", data: "i-03e", wantErr: ErrLeadingZero, wantOffset: 1},
+		{name: "Leading Zero Length", data: "03:abc", wantErr: ErrLeadingZeroLength, wantOffset: 0},
+		{name: "Trailing Data", data: "dei1e", wantErr: ErrTrailingData, wantOffset: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got interface{}
+			err := DecodeStrict([]byte(tt.data), &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DecodeStrict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				return
+			}
+			var canonicalErr *CanonicalError
+			if !errors.As(err, &canonicalErr) || canonicalErr.Offset != tt.wantOffset {
+				t.Errorf("DecodeStrict() error = %v, want offset %d", err, tt.wantOffset)
+			}
+
+			// the same input is accepted leniently with a single warning
+			warnings, err := DecodeLenient([]byte(tt.data), &got)
+			if err != nil {
+				t.Fatalf("DecodeLenient() error = %v", err)
+			}
+			if len(warnings) != 1 || !errors.Is(warnings[0], tt.wantErr) || warnings[0].Offset != tt.wantOffset {
+				t.Errorf("DecodeLenient() warnings = %v", warnings)
+			}
+		})
+	}
+}
+
+func TestDecodeLenient_MultipleWarnings(t *testing.T) {
+	var target struct {
+		Name   string `bencode:"name"`
+		Length int64  `bencode:"length"`
+	}
+	warnings, err := DecodeLenient([]byte("d4:name04:test6:lengthi007eejunk"), &target)
+	if err != nil {
+		t.Fatalf("DecodeLenient() error = %v", err)
+	}
+	if target.Name != "test" || target.Length != 7 {
+		t.Errorf("DecodeLenient() got = %+v", target)
+	}
+
+	want := []error{ErrLeadingZeroLength, ErrUnsortedKeys, ErrLeadingZero, ErrTrailingData}
+	if len(warnings) != len(want) {
+		t.Fatalf("DecodeLenient() warnings = %v, want %v", warnings, want)
+	}
+	for i, warning := range warnings {
+		if !errors.Is(warning, want[i]) {
+			t.Errorf("warning %d got = %v, want %v", i, warning, want[i])
+		}
+	}
+}
+
+func TestDecoder_DisallowNonCanonical(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("i1ei01e"))
+	decoder.DisallowNonCanonical()
+
+	var value int64
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if err := decoder.Decode(&value); !errors.Is(err, ErrLeadingZero) {
+		t.Errorf("Decode() error = %v, want %v", err, ErrLeadingZero)
+	}
+}
+
+func TestDecoder_WarningsCoverLastValue(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(strings.Repeat("i01e", 1000) + "i1e"))
+	var value int64
+	var first []*CanonicalError
+	for i := 0; i < 1000; i++ {
+		if err := decoder.Decode(&value); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if warnings := decoder.Warnings(); len(warnings) != 1 || !errors.Is(warnings[0], ErrLeadingZero) {
+			t.Fatalf("Warnings() after value %d = %v", i, warnings)
+		}
+		if i == 0 {
+			first = decoder.Warnings()
+		}
+	}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if warnings := decoder.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() after a canonical value = %v", warnings)
+	}
+	if len(first) != 1 || first[0].Offset != 1 {
+		t.Errorf("warnings returned earlier changed: %v", first)
+	}
+}
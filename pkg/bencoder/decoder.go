@@ -1,195 +1,608 @@
 package bencoder
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
-	"golang.org/x/exp/slices"
+	"io"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 )
 
-func getDecoder(data []byte) func([]byte) (interface{}, error) {
-	var decodeFuncs = map[byte]func([]byte) (interface{}, error){
-		'i': decodeInt,
-		'l': decodeList,
-		'd': decodeDict,
-	}
+// maxIntegerDigits and maxLengthDigits bound the textual size of integers
+// and string length prefixes, so a stream that never sends the closing 'e'
+// or ':' cannot make the Decoder buffer forever. Integers may be larger than
+// 64 bits; lengths never are.
+const (
+	maxIntegerDigits = 1024
+	maxLengthDigits  = 20
+)
+
+// stringChunkSize is the largest string allocated up front, before its
+// bytes have actually arrived.
+const stringChunkSize = 64 << 10
+
+// byteReader is what the Decoder needs from its input: single byte reads so
+// it never consumes bytes that belong to whatever follows the current value.
+type byteReader interface {
+	io.Reader
+	io.ByteScanner
+}
+
+// Decoder reads and decodes bencoded values from an input stream.
+type Decoder struct {
+	r      byteReader
+	offset int64
+
+	// raw collects the bytes read while recording is set, which is how a
+	// RawMessage receives the exact encoding of its value.
+	raw       []byte
+	recording bool
+
+	// savedError is the first value that could not be stored in its target.
+	// Decoding carries on past it so the rest of the document is still used.
+	savedError error
+	// path locates the value being decoded for UnmarshalTypeError.
+	path []pathSegment
+
+	strict   bool
+	warnings []*CanonicalError
+
+	// limits and the usage of the value being decoded counted against them
+	limits     Limits
+	valueStart int64
+	depth      int
+	items      int64
 
-	if decodeFunc, ok := decodeFuncs[data[0]]; ok {
-		return decodeFunc
+	// scratch space reused between tokens: digits of the current number,
+	// strings that are not kept, and the keys of the dicts being read
+	digits   []byte
+	scratch  []byte
+	keyBytes []byte
+	keyEnds  []int
+}
+
+// NewDecoder returns a decoder that reads from r. If r already supports
+// byte-wise reads (bufio.Reader, bytes.Reader) it is used directly, so the
+// caller can keep reading raw data from r after a value has been decoded.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
 	}
-	return decodeString
+	return &Decoder{r: br, limits: DefaultLimits}
 }
 
-func decodeNestedInt(data []byte, startIndex int) (int, interface{}, error) {
-	if len(data) <= 3 || data[startIndex] != 'i' {
-		return 0, nil, fmt.Errorf("invalid integer format: %s", string(data))
+// Decode reads the next bencoded value from the input and stores it in the
+// value pointed to by v. It returns io.EOF when the input is exhausted
+// before a new value starts.
+func (d *Decoder) Decode(v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return errors.New("non-nil pointer required for decoding")
 	}
-	// find the endIndex
-	endIndex := startIndex + 1
-	for data[endIndex] != byte('e') {
-		endIndex += 1
-		if endIndex > len(data) {
-			// WTF why are you passing this asshole it's not an int
-			return 0, nil, fmt.Errorf("invalid integer format: %s", string(data))
-		}
+
+	d.valueStart = d.offset
+	d.depth = 0
+	d.items = 0
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return err
 	}
-	result, err := decodeInt(data[startIndex : endIndex+1])
-	return endIndex + 1, result, err
+	d.offset++
+
+	d.savedError = nil
+	d.warnings = nil
+	d.path = d.path[:0]
+	d.keyBytes = d.keyBytes[:0]
+	d.keyEnds = d.keyEnds[:0]
+	if err := d.decodeInto(b, target.Elem()); err != nil {
+		return err
+	}
+	return d.savedError
+}
+
+// InputOffset returns the number of bytes consumed by the values decoded so far.
+func (d *Decoder) InputOffset() int64 {
+	return d.offset
 }
 
-func decodeInt(data []byte) (interface{}, error) {
-	if len(data) < 3 || data[0] != 'i' || data[len(data)-1] != 'e' {
-		return nil, fmt.Errorf("invalid integer format: %s", string(data))
+// Buffered returns a reader of the data remaining in the Decoder's buffer
+// that has not been consumed by a decoded value.
+func (d *Decoder) Buffered() io.Reader {
+	if br, ok := d.r.(*bufio.Reader); ok {
+		data, _ := br.Peek(br.Buffered())
+		return bytes.NewReader(data)
 	}
-	return strconv.ParseInt(string(data[1:len(data)-1]), 10, 64)
+	return d.r
 }
 
-func decodeString(data []byte) (interface{}, error) {
-	_, result, err := decodeNestedString(data, 0)
+// next reads the following byte of a value that has already started, so
+// running out of input is reported as a SyntaxError wrapping
+// io.ErrUnexpectedEOF.
+func (d *Decoder) next(expected string) (byte, error) {
+	if err := d.checkSize(1); err != nil {
+		return 0, err
+	}
+	b, err := d.r.ReadByte()
+	if err == io.EOF {
+		return 0, d.unexpectedEOF(expected)
+	}
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	d.offset++
+	if d.recording {
+		d.raw = append(d.raw, b)
+	}
+	return b, nil
+}
 
-	lengthPart, err := strconv.Atoi(string(data[0 : len(data)-len(result)-1]))
-	if err != nil || lengthPart != len(result) {
-		return nil, errors.New("mismatch of length and byte string")
+func (d *Decoder) syntaxError(offset int64, expected string, format string, args ...interface{}) error {
+	return &SyntaxError{Offset: offset, Expected: expected, msg: fmt.Sprintf(format, args...)}
+}
+
+func (d *Decoder) unexpectedEOF(expected string) error {
+	return &SyntaxError{Offset: d.offset, Expected: expected, msg: "unexpected end of input", err: io.ErrUnexpectedEOF}
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// isInteger reports whether digits is an optionally negative decimal number.
+func isInteger(digits []byte) bool {
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
 	}
+	return isNatural(digits)
+}
 
-	return result, nil
+// isNatural reports whether digits is a non-empty unsigned decimal number.
+func isNatural(digits []byte) bool {
+	if len(digits) == 0 {
+		return false
+	}
+	for _, c := range digits {
+		if !isDigit(c) {
+			return false
+		}
+	}
+	return true
 }
 
-func decodeNestedString(data []byte, startIndex int) (int, []byte, error) {
-	// It's byte string not normal string so we will return the bytes
-	// Very handy too since the piece hash is in bytes
-	separator := []byte(":")
-	lengthBytes, stringBytes, found := bytes.Cut(data, separator)
-	if !found {
-		return 0, nil, errors.New("unknown format")
+// parseMagnitude parses validated integer digits, reporting false if the
+// magnitude does not fit in a uint64.
+func parseMagnitude(digits []byte) (magnitude uint64, negative bool, ok bool) {
+	if digits[0] == '-' {
+		negative = true
+		digits = digits[1:]
+	}
+	for _, c := range digits {
+		if magnitude > math.MaxUint64/10 {
+			return 0, false, false
+		}
+		next := magnitude*10 + uint64(c-'0')
+		if next < magnitude*10 {
+			return 0, false, false
+		}
+		magnitude = next
 	}
+	return magnitude, negative, true
+}
 
-	length, err := strconv.Atoi(string(lengthBytes))
-	if err != nil {
-		return 0, nil, errors.New("length of string is not correct")
+// parseInt is strconv.ParseInt over validated digits, without converting
+// them to a string first.
+func parseInt(digits []byte, bitSize int) (int64, bool) {
+	magnitude, negative, ok := parseMagnitude(digits)
+	if !ok {
+		return 0, false
+	}
+	limit := uint64(1) << uint(bitSize-1)
+	if negative {
+		if magnitude > limit {
+			return 0, false
+		}
+		return int64(^magnitude + 1), true
+	}
+	if magnitude >= limit {
+		return 0, false
 	}
+	return int64(magnitude), true
+}
 
-	if length > len(stringBytes) {
-		return 0, nil, errors.New("mismatch of length and byte string")
+// parseUint is strconv.ParseUint over validated digits.
+func parseUint(digits []byte, bitSize int) (uint64, bool) {
+	magnitude, negative, ok := parseMagnitude(digits)
+	if !ok || negative {
+		return 0, false
+	}
+	if bitSize < 64 && magnitude >= uint64(1)<<uint(bitSize) {
+		return 0, false
 	}
+	return magnitude, true
+}
 
-	colonIndex := slices.IndexFunc(data, func(e byte) bool { return e == byte(':') })
-	stringBytes = stringBytes[:length]
-	nextIndex := startIndex + colonIndex + length + 1
-	return nextIndex, stringBytes, nil
+func (d *Decoder) readKey(b byte) ([]byte, error) {
+	if !isDigit(b) {
+		return nil, d.syntaxError(d.offset-1, "string key", "invalid dictionary key %q", b)
+	}
+	return d.readString(b)
 }
 
-func decodeList(data []byte) (interface{}, error) {
-	_, result, err := decodeNestedList(data, 0)
-	return result, err
+// readKeyScratch is readKey returning a slice of the decoder's scratch
+// space, valid until the next string is read.
+func (d *Decoder) readKeyScratch(b byte) ([]byte, error) {
+	if !isDigit(b) {
+		return nil, d.syntaxError(d.offset-1, "string key", "invalid dictionary key %q", b)
+	}
+	return d.readStringScratch(b)
 }
 
-func decodeNestedList(data []byte, startIndex int) (nextIndex int, result interface{}, err error) {
-	result = []interface{}{}
-	err = fmt.Errorf("list element format invalid")
-	nextIndex = startIndex + 1 // skip the first l
+func (d *Decoder) readElement(b byte) (interface{}, error) {
+	switch {
+	case b == 'i':
+		return d.readInt()
+	case b == 'l':
+		return d.readList()
+	case b == 'd':
+		return d.readDict()
+	case isDigit(b):
+		return d.readString(b)
+	}
+	return nil, d.syntaxError(d.offset-1, "value", "invalid character %q", b)
+}
 
-	if len(data) < nextIndex || data[startIndex] != 'l' {
-		return
+// skipValue reads past the value starting with b, checking it the same way
+// as readElement but without building it.
+func (d *Decoder) skipValue(b byte) error {
+	switch {
+	case b == 'i':
+		_, err := d.readIntDigits()
+		return err
+	case b == 'l':
+		return d.skipList()
+	case b == 'd':
+		return d.skipDict()
+	case isDigit(b):
+		return d.skipString(b)
 	}
+	return d.syntaxError(d.offset-1, "value", "invalid character %q", b)
+}
 
-	var element any
-	var innerErr error
+func (d *Decoder) skipList() error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
 
-	for data[nextIndex] != byte('e') {
-		nextIndex, element, innerErr = decodeNestedElement(data, nextIndex)
-		if innerErr != nil {
-			result = nil
-			return
+	for {
+		b, err := d.next("list element or 'e'")
+		if err != nil {
+			return err
 		}
-		result = append(result.([]interface{}), element)
-		if nextIndex >= len(data) {
-			result = nil
-			err = fmt.Errorf("list element format invalid")
-			return
+		if b == 'e' {
+			return nil
+		}
+		if err := d.countItem(); err != nil {
+			return err
+		}
+		if err := d.skipValue(b); err != nil {
+			return err
 		}
 	}
+}
 
-	nextIndex += 1 // skip the last e
-	err = nil
-	return
+func (d *Decoder) skipDict() error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	keys := d.beginKeys()
+	defer d.endKeys(keys)
+	for {
+		b, err := d.next("dictionary key or 'e'")
+		if err != nil {
+			return err
+		}
+		if b == 'e' {
+			return nil
+		}
+		if err := d.countItem(); err != nil {
+			return err
+		}
+		start := d.offset - 1
+		key, err := d.readKeyScratch(b)
+		if err != nil {
+			return err
+		}
+		if err := d.addKey(keys, start, key); err != nil {
+			return err
+		}
+		b, err = d.next("dictionary value")
+		if err != nil {
+			return err
+		}
+		if err := d.skipValue(b); err != nil {
+			return err
+		}
+	}
 }
 
-func decodeDict(data []byte) (interface{}, error) {
-	_, result, err := decodeNestedDict(data, 0)
-	return result, err
+// dictKeys marks where the keys of one dict start in the decoder's key
+// scratch space. Nested dicts stack their keys after those of their parent.
+type dictKeys struct {
+	bytes    int
+	ends     int
+	unsorted bool
 }
 
-func decodeNestedDict(data []byte, startIndex int) (nextIndex int, result interface{}, err error) {
-	result = map[string]interface{}{}
-	err = fmt.Errorf("invalid dictionary format")
-	nextIndex = startIndex + 1 // skip the first d
+func (d *Decoder) beginKeys() *dictKeys {
+	return &dictKeys{bytes: len(d.keyBytes), ends: len(d.keyEnds)}
+}
 
-	if len(data) < nextIndex || data[startIndex] != 'd' {
-		return
+func (d *Decoder) endKeys(keys *dictKeys) {
+	d.keyBytes = d.keyBytes[:keys.bytes]
+	d.keyEnds = d.keyEnds[:keys.ends]
+}
+
+// addKey records the next key of a dict, rejecting duplicates and checking
+// the key order. Canonical input only ever compares against the previous
+// key; the earlier keys are searched only once the order is broken.
+func (d *Decoder) addKey(keys *dictKeys, offset int64, key []byte) error {
+	if count := len(d.keyEnds) - keys.ends; count > 0 {
+		previous := d.keyBytes[d.keyStart(keys, count-1):]
+		if keys.unsorted || bytes.Compare(previous, key) >= 0 {
+			keys.unsorted = true
+			for i := 0; i < count; i++ {
+				end := d.keyEnds[keys.ends+i]
+				if bytes.Equal(d.keyBytes[d.keyStart(keys, i):end], key) {
+					return d.syntaxError(offset, "unique key", "duplicate dictionary key %q", key)
+				}
+			}
+			if err := d.checkKeyOrder(offset, previous, key); err != nil {
+				return err
+			}
+		}
 	}
+	d.keyBytes = append(d.keyBytes, key...)
+	d.keyEnds = append(d.keyEnds, len(d.keyBytes))
+	return nil
+}
 
-	var element any
-	var key []byte
-	var innerErr error
+// keyStart returns where the i-th key of the dict starts.
+func (d *Decoder) keyStart(keys *dictKeys, i int) int {
+	if i == 0 {
+		return keys.bytes
+	}
+	return d.keyEnds[keys.ends+i-1]
+}
 
-	for data[nextIndex] != byte('e') {
-		// first extract the key and move the index after the extracted key string
-		nextIndex, key, innerErr = decodeNestedString(data[nextIndex:], nextIndex)
-		if innerErr != nil || len(data) < nextIndex {
-			result = nil
-			return
+// readDigits collects bytes up to the terminator, first being already
+// consumed. The result is only valid until the next number is read.
+func (d *Decoder) readDigits(first byte, terminator byte, limit int) ([]byte, error) {
+	digits := d.digits[:0]
+	if first != 0 {
+		digits = append(digits, first)
+	}
+	for {
+		b, err := d.next(terminatorNames[terminator])
+		if err != nil {
+			return nil, err
 		}
-		if _, exists := result.(map[string]interface{})[string(key)]; exists {
-			result = nil
-			return
+		if b == terminator {
+			d.digits = digits[:0] // keep any growth for the next number
+			return digits, nil
 		}
-		// now get the value
-		nextIndex, element, innerErr = decodeNestedElement(data, nextIndex)
-		if innerErr != nil {
-			result = nil
-			return
+		if len(digits) == limit {
+			return nil, d.syntaxError(d.offset-1, terminatorNames[terminator], "number too long")
+		}
+		digits = append(digits, b)
+	}
+}
+
+var terminatorNames = map[byte]string{'e': "'e'", ':': "':'"}
+
+// readIntDigits reads the text of an integer whose 'i' has been consumed.
+func (d *Decoder) readIntDigits() ([]byte, error) {
+	start := d.offset
+	digits, err := d.readDigits(0, 'e', maxIntegerDigits)
+	if err != nil {
+		return nil, err
+	}
+	if !isInteger(digits) {
+		return nil, d.syntaxError(start, "decimal integer", "invalid integer format %q", digits)
+	}
+	if err := d.checkInteger(start, digits); err != nil {
+		return nil, err
+	}
+	return digits, nil
+}
+
+// readInt reads an integer as an int64 or, when it does not fit, a *big.Int.
+func (d *Decoder) readInt() (interface{}, error) {
+	digits, err := d.readIntDigits()
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := parseInt(digits, 64); ok {
+		return value, nil
+	}
+	value, _ := new(big.Int).SetString(string(digits), 10)
+	return value, nil
+}
+
+// readLength reads the length prefix of a string whose first digit has
+// been consumed.
+func (d *Decoder) readLength(first byte) (int, error) {
+	start := d.offset - 1
+	digits, err := d.readDigits(first, ':', maxLengthDigits)
+	if err != nil {
+		return 0, err
+	}
+	length, ok := parseInt(digits, strconv.IntSize)
+	if !isNatural(digits) || !ok {
+		return 0, d.syntaxError(start, "string length", "invalid string length %q", digits)
+	}
+	if err := d.checkLength(start, digits); err != nil {
+		return 0, err
+	}
+	if err := d.checkStringLength(start, int(length)); err != nil {
+		return 0, err
+	}
+	return int(length), nil
+}
+
+func (d *Decoder) readString(first byte) ([]byte, error) {
+	length, err := d.readLength(first)
+	if err != nil {
+		return nil, err
+	}
+	return d.readStringBody(length, nil)
+}
+
+// readStringScratch is readString returning a slice of the decoder's
+// scratch space, valid until the next string is read.
+func (d *Decoder) readStringScratch(first byte) ([]byte, error) {
+	length, err := d.readLength(first)
+	if err != nil {
+		return nil, err
+	}
+	if length <= stringChunkSize && cap(d.scratch) < length {
+		d.scratch = make([]byte, length)
+	}
+	return d.readStringBody(length, d.scratch)
+}
+
+// skipString reads past a string. Long strings that are not being recorded
+// are discarded without being buffered.
+func (d *Decoder) skipString(first byte) error {
+	length, err := d.readLength(first)
+	if err != nil {
+		return err
+	}
+	if length <= stringChunkSize || d.recording {
+		if length <= stringChunkSize && cap(d.scratch) < length {
+			d.scratch = make([]byte, length)
 		}
-		result.(map[string]interface{})[string(key)] = element
-		if nextIndex >= len(data) {
-			result = nil
-			return
+		_, err := d.readStringBody(length, d.scratch)
+		return err
+	}
+
+	n, err := io.CopyN(io.Discard, d.r, int64(length))
+	d.offset += n
+	if err == io.EOF {
+		return d.unexpectedEOF(fmt.Sprintf("string of length %d", length))
+	}
+	return err
+}
+
+// readStringBody reads the bytes of a string whose length prefix has been
+// consumed, into buffer if it is large enough.
+func (d *Decoder) readStringBody(length int, buffer []byte) ([]byte, error) {
+	result, err := d.readBytes(length, buffer)
+	d.offset += int64(len(result))
+	if d.recording {
+		d.raw = append(d.raw, result...)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, d.unexpectedEOF(fmt.Sprintf("string of length %d", length))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readBytes reads length bytes, returning what was read on error. Long
+// strings are read in chunks so a bogus length prefix cannot allocate more
+// memory than the input actually holds.
+func (d *Decoder) readBytes(length int, buffer []byte) ([]byte, error) {
+	if length <= stringChunkSize {
+		if cap(buffer) < length {
+			buffer = make([]byte, length)
 		}
+		result := buffer[:length]
+		n, err := io.ReadFull(d.r, result)
+		return result[:n], err
 	}
 
-	nextIndex += 1 // skip the last e
-	err = nil
-	return
+	var chunks bytes.Buffer
+	chunks.Grow(stringChunkSize)
+	n, err := io.CopyN(&chunks, d.r, int64(length))
+	if err == nil && n < int64(length) {
+		err = io.ErrUnexpectedEOF
+	}
+	return chunks.Bytes(), err
 }
 
-func decodeNestedElement(data []byte, index int) (nextIndex int, element any, err error) {
-	decoderFunc := getDecoder(data[index : index+1])
-	switch reflect.ValueOf(decoderFunc).Pointer() {
-	case reflect.ValueOf(decodeInt).Pointer():
-		nextIndex, element, err = decodeNestedInt(data, index)
+func (d *Decoder) readList() (interface{}, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
+	result := []interface{}{}
+	for {
+		b, err := d.next("list element or 'e'")
+		if err != nil {
+			return nil, err
+		}
+		if b == 'e' {
+			return result, nil
+		}
+		if err := d.countItem(); err != nil {
+			return nil, err
+		}
+		element, err := d.readElement(b)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, element)
+	}
+}
+
+func (d *Decoder) readDict() (interface{}, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
+	result := map[string]interface{}{}
+	keys := d.beginKeys()
+	defer d.endKeys(keys)
+	for {
+		b, err := d.next("dictionary key or 'e'")
 		if err != nil {
-			return
+			return nil, err
+		}
+		if b == 'e' {
+			return result, nil
 		}
-	case reflect.ValueOf(decodeString).Pointer():
-		nextIndex, element, err = decodeNestedString(data[index:], index)
+		if err := d.countItem(); err != nil {
+			return nil, err
+		}
+		start := d.offset - 1
+		key, err := d.readKeyScratch(b)
 		if err != nil {
-			return
+			return nil, err
+		}
+		if err := d.addKey(keys, start, key); err != nil {
+			return nil, err
 		}
-	case reflect.ValueOf(decodeList).Pointer():
-		nextIndex, element, err = decodeNestedList(data, index)
+		name := string(key)
+		b, err = d.next("dictionary value")
 		if err != nil {
-			return
+			return nil, err
 		}
-	case reflect.ValueOf(decodeDict).Pointer():
-		nextIndex, element, err = decodeNestedDict(data, index)
+		element, err := d.readElement(b)
 		if err != nil {
-			return
+			return nil, err
 		}
+		result[name] = element
 	}
-	return
 }
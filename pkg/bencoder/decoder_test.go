@@ -0,0 +1,114 @@
+package bencoder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []interface{}
+		offsets []int64
+		wantErr error
+	}{
+		{
+			name:    "Single Value",
+			data:    "i42e",
+			want:    []interface{}{int64(42)},
+			offsets: []int64{4},
+		},
+		{
+			name:    "Back To Back Values",
+			data:    "i1e4:spamd3:fooli2eee",
+			want:    []interface{}{int64(1), []byte("spam"), map[string]interface{}{"foo": []interface{}{int64(2)}}},
+			offsets: []int64{3, 9, 21},
+		},
+		{
+			name:    "Truncated Dict",
+			data:    "d3:foo",
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "Truncated String",
+			data:    "10:spam",
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a one byte reader makes sure nothing relies on large reads
+			decoder := NewDecoder(iotest.OneByteReader(strings.NewReader(tt.data)))
+			for i, want := range tt.want {
+				var got interface{}
+				if err := decoder.Decode(&got); err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Decode() got = %v, want %v", got, want)
+				}
+				if decoder.InputOffset() != tt.offsets[i] {
+					t.Errorf("InputOffset() got = %d, want %d", decoder.InputOffset(), tt.offsets[i])
+				}
+			}
+			var rest interface{}
+			err := decoder.Decode(&rest)
+			if tt.wantErr == nil {
+				tt.wantErr = io.EOF
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecoder_DecodeStructFollowedByRawData(t *testing.T) {
+	// ut_metadata data messages carry a bencoded dict followed by the raw piece
+	reader := bytes.NewReader([]byte("d8:msg_typei1e5:piecei0e10:total_sizei8eeRAWBYTES"))
+	var message struct {
+		MsgType   int64 `bencode:"msg_type"`
+		Piece     int64 `bencode:"piece"`
+		TotalSize int64 `bencode:"total_size"`
+	}
+
+	decoder := NewDecoder(reader)
+	if err := decoder.Decode(&message); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if message.MsgType != 1 || message.Piece != 0 || message.TotalSize != 8 {
+		t.Errorf("Decode() got = %+v", message)
+	}
+	if decoder.InputOffset() != 41 {
+		t.Errorf("InputOffset() got = %d, want 41", decoder.InputOffset())
+	}
+
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(rest) != "RAWBYTES" {
+		t.Errorf("remaining data got = %q, want %q", rest, "RAWBYTES")
+	}
+}
+
+func TestDecoder_Buffered(t *testing.T) {
+	decoder := NewDecoder(io.MultiReader(strings.NewReader("i7etrailer")))
+	var got int64
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got != 7 {
+		t.Errorf("Decode() got = %d, want 7", got)
+	}
+	rest, _ := io.ReadAll(decoder.Buffered())
+	if string(rest) != "trailer" {
+		t.Errorf("Buffered() got = %q, want %q", rest, "trailer")
+	}
+}
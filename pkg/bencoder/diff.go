@@ -0,0 +1,182 @@
+package bencoder
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ChangeKind says how a value differs between two documents.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota + 1
+	Removed
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return "invalid"
+}
+
+// Change is one difference found by Diff. Old is the zero Value for an
+// added key and New is for a removed one.
+type Change struct {
+	Kind ChangeKind
+	Path string // key path to the value, e.g. info.files[3].length
+	Old  Value
+	New  Value
+}
+
+// String formats the change as a single line, with values summarised. The
+// top-level value has the path ".".
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "."
+	}
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", path, Summarize(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", path, Summarize(c.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", path, Summarize(c.Old), Summarize(c.New))
+}
+
+// Diff compares two bencoded documents and returns what changed from a to
+// b. Dicts are compared key by key and lists index by index; any other
+// difference, including a value changing kind, is reported at its path.
+// Values that are equal produce no change.
+func Diff(a, b []byte) ([]Change, error) {
+	before, err := ParseValue(a)
+	if err != nil {
+		return nil, fmt.Errorf("first document: %w", err)
+	}
+	after, err := ParseValue(b)
+	if err != nil {
+		return nil, fmt.Errorf("second document: %w", err)
+	}
+	return DiffValues(before, after), nil
+}
+
+// DiffValues compares two values as Diff does.
+func DiffValues(before, after Value) []Change {
+	var changes []Change
+	diffValues(&changes, nil, before, after)
+	return changes
+}
+
+func diffValues(changes *[]Change, path []pathSegment, before, after Value) {
+	switch {
+	case before.Kind() == DictKind && after.Kind() == DictKind:
+		diffDicts(changes, path, before, after)
+	case before.Kind() == ListKind && after.Kind() == ListKind:
+		diffLists(changes, path, before, after)
+	case !before.Equal(after):
+		*changes = append(*changes, Change{Kind: Changed, Path: formatPath(path), Old: before, New: after})
+	}
+}
+
+func diffDicts(changes *[]Change, path []pathSegment, before, after Value) {
+	beforeEntries, _ := before.Dict()
+	afterEntries, _ := after.Dict()
+	// index both sides once; lenient input need not have sorted keys to
+	// merge, and Get would make large dicts quadratic
+	beforeValues := dictIndex(beforeEntries)
+	afterValues := dictIndex(afterEntries)
+	for _, entry := range beforeEntries {
+		entryPath := appendKey(path, entry.Key)
+		if value, ok := afterValues[string(entry.Key)]; ok {
+			diffValues(changes, entryPath, entry.Value, value)
+		} else {
+			*changes = append(*changes, Change{Kind: Removed, Path: formatPath(entryPath), Old: entry.Value})
+		}
+	}
+	for _, entry := range afterEntries {
+		if _, ok := beforeValues[string(entry.Key)]; !ok {
+			*changes = append(*changes, Change{Kind: Added, Path: formatPath(appendKey(path, entry.Key)), New: entry.Value})
+		}
+	}
+}
+
+// dictIndex maps the keys of entries to their values.
+func dictIndex(entries []DictEntry) map[string]Value {
+	index := make(map[string]Value, len(entries))
+	for _, entry := range entries {
+		if _, ok := index[string(entry.Key)]; !ok {
+			index[string(entry.Key)] = entry.Value
+		}
+	}
+	return index
+}
+
+func diffLists(changes *[]Change, path []pathSegment, before, after Value) {
+	beforeElements, _ := before.List()
+	afterElements, _ := after.List()
+	for i := 0; i < len(beforeElements) || i < len(afterElements); i++ {
+		elementPath := append(path[:len(path):len(path)], pathSegment{index: i, isIndex: true})
+		switch {
+		case i >= len(afterElements):
+			*changes = append(*changes, Change{Kind: Removed, Path: formatPath(elementPath), Old: beforeElements[i]})
+		case i >= len(beforeElements):
+			*changes = append(*changes, Change{Kind: Added, Path: formatPath(elementPath), New: afterElements[i]})
+		default:
+			diffValues(changes, elementPath, beforeElements[i], afterElements[i])
+		}
+	}
+}
+
+// appendKey returns path extended by key, writing binary keys in hex.
+func appendKey(path []pathSegment, key []byte) []pathSegment {
+	name := string(key)
+	if !utf8.Valid(key) {
+		name = "0x" + hex.EncodeToString(key)
+	}
+	return append(path[:len(path):len(path)], pathSegment{key: name})
+}
+
+// maxSummaryBytes is how much of a byte string Summarize shows.
+const maxSummaryBytes = 32
+
+// Summarize describes v in a short single line: integers in full, text
+// quoted, binary strings by their length and first bytes in hex, and lists
+// and dicts by their size.
+func Summarize(v Value) string {
+	switch v.Kind() {
+	case IntKind, BigIntKind:
+		value, _ := v.BigInt()
+		return value.String()
+	case BytesKind:
+		data, _ := v.Bytes()
+		if utf8.Valid(data) {
+			if len(data) <= maxSummaryBytes {
+				return strconv.Quote(string(data))
+			}
+			// cut on a rune boundary
+			end := maxSummaryBytes
+			for !utf8.RuneStart(data[end]) {
+				end--
+			}
+			return fmt.Sprintf("%s... (%d bytes)", strconv.Quote(string(data[:end])), len(data))
+		}
+		if len(data) <= maxSummaryBytes/2 {
+			return fmt.Sprintf("<%d bytes %s>", len(data), hex.EncodeToString(data))
+		}
+		return fmt.Sprintf("<%d bytes %s...>", len(data), hex.EncodeToString(data[:maxSummaryBytes/2]))
+	case ListKind:
+		return fmt.Sprintf("list of %d", v.Len())
+	case DictKind:
+		return fmt.Sprintf("dict of %d", v.Len())
+	}
+	return "nothing"
+}
@@ -0,0 +1,148 @@
+package bencoder
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	pieces := strings.Repeat("\xaa", 20)
+	otherPieces := strings.Repeat("\xbb", 20)
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{
+			name: "equal",
+			a:    "d4:infod4:name4:demoee",
+			b:    "d4:infod4:name4:demoee",
+			want: nil,
+		},
+		{
+			name: "added and removed keys",
+			a:    "d7:comment2:hi4:infod4:name4:demoee",
+			b:    "d4:infod4:name4:demo6:source3:abcee",
+			want: []string{
+				`- comment: "hi"`,
+				`+ info.source: "abc"`,
+			},
+		},
+		{
+			name: "changed values inside lists",
+			a:    "d4:infod5:filesld6:lengthi1eed6:lengthi2eeeee",
+			b:    "d4:infod5:filesld6:lengthi1eed6:lengthi3eed6:lengthi4eeeee",
+			want: []string{
+				"~ info.files[1].length: 2 -> 3",
+				"+ info.files[2]: dict of 1",
+			},
+		},
+		{
+			name: "binary values summarised",
+			a:    "d6:pieces20:" + pieces + "e",
+			b:    "d6:pieces20:" + otherPieces + "e",
+			want: []string{
+				"~ pieces: <20 bytes aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa...> -> <20 bytes bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb...>",
+			},
+		},
+		{
+			name: "kind changed",
+			a:    "d7:privatei1ee",
+			b:    "d7:private1:1e",
+			want: []string{`~ private: 1 -> "1"`},
+		},
+		{
+			name: "binary key",
+			a:    "d2:\xff\x00i1ee",
+			b:    "de",
+			want: []string{"- 0xff00: 1"},
+		},
+		{
+			name: "top level",
+			a:    "i1e",
+			b:    "i2e",
+			want: []string{"~ .: 1 -> 2"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes, err := Diff([]byte(test.a), []byte(test.b))
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Diff() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiff_Values(t *testing.T) {
+	changes, err := Diff([]byte("d1:ai1e1:bi2ee"), []byte("d1:ai5e1:ci3ee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Kind: Changed, Path: "a", Old: NewInt(1), New: NewInt(5)},
+		{Kind: Removed, Path: "b", Old: NewInt(2)},
+		{Kind: Added, Path: "c", New: NewInt(3)},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff() = %+v, want %+v", changes, want)
+	}
+}
+
+func TestDiff_LargeDict(t *testing.T) {
+	// as big as the piece layers of a large v2 torrent
+	build := func(changed int) string {
+		var builder strings.Builder
+		builder.WriteString("d")
+		for i := 0; i < 50000; i++ {
+			value := 0
+			if i == changed {
+				value = 1
+			}
+			fmt.Fprintf(&builder, "6:k%05di%de", i, value)
+		}
+		builder.WriteString("e")
+		return builder.String()
+	}
+	changes, err := Diff([]byte(build(-1)), []byte(build(31337)))
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].String() != "~ k31337: 0 -> 1" {
+		t.Errorf("Diff() = %v", changes)
+	}
+}
+
+func TestDiff_InvalidInput(t *testing.T) {
+	if _, err := Diff([]byte("de"), []byte("d")); err == nil || !strings.HasPrefix(err.Error(), "second document") {
+		t.Errorf("Diff() error = %v, want a second document error", err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	long := strings.Repeat("é", 20)
+	tests := []struct {
+		value Value
+		want  string
+	}{
+		{NewString("demo"), `"demo"`},
+		{NewString(long), `"éééééééééééééééé"... (40 bytes)`},
+		{NewBytes([]byte{0, 1, 0xff}), "<3 bytes 0001ff>"},
+		{NewList(NewInt(1), NewInt(2)), "list of 2"},
+		{Value{}, "nothing"},
+	}
+	for _, test := range tests {
+		if got := Summarize(test.value); got != test.want {
+			t.Errorf("Summarize() = %s, want %s", got, test.want)
+		}
+	}
+}
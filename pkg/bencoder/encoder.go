@@ -1,118 +1,335 @@
 package bencoder
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"sort"
+	"sync"
 )
 
-func encode(data interface{}) ([]byte, error) {
-	if data == nil {
-		return nil, errors.New("no data to encode")
+// maxPooledBuffer keeps a single huge document from pinning its buffer in the pool.
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+var writerPool = sync.Pool{
+	New: func() interface{} { return bufio.NewWriter(nil) },
+}
+
+// tokenWriter is implemented by both bytes.Buffer and bufio.Writer. Neither
+// reports write errors per call: bytes.Buffer cannot fail and bufio.Writer
+// keeps the first error and returns it from Flush.
+type tokenWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// Encoder writes bencoded values to an output stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns an encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the bencoding of v to the stream. Tokens are written as
+// they are produced, so if encoding fails part of v may already be written.
+func (e *Encoder) Encode(v interface{}) error {
+	writer := writerPool.Get().(*bufio.Writer)
+	writer.Reset(e.w)
+	defer func() {
+		writer.Reset(nil)
+		writerPool.Put(writer)
+	}()
+
+	if err := writeValue(writer, reflect.ValueOf(v)); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// Marshal returns the bencoding of v, building it in a pooled buffer.
+func Marshal(v interface{}) ([]byte, error) {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	defer func() {
+		if buffer.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buffer)
+		}
+	}()
+
+	if err := writeValue(buffer, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	result := make([]byte, buffer.Len())
+	copy(result, buffer.Bytes())
+	return result, nil
+}
+
+func writeValue(w tokenWriter, value reflect.Value) error {
+	if !value.IsValid() {
+		return errors.New("no data to encode")
+	}
+	return typeEncoder(value.Type())(w, value)
+}
+
+// newTypeEncoder builds the encoder for t. With allowAddr set, methods with
+// pointer receivers are used whenever the value being encoded is addressable.
+func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	switch t {
+	case valueType:
+		return valueEncoder
+	case bigIntType:
+		return bigIntEncoder
+	case reflect.PtrTo(bigIntType):
+		return newPtrEncoder(t)
+	}
+	if t.Kind() != reflect.Interface {
+		if t.Kind() != reflect.Ptr && allowAddr && reflect.PtrTo(t).Implements(marshalerType) {
+			return condAddrEncoder(addrMarshalerEncoder, newTypeEncoder(t, false))
+		}
+		if t.Implements(marshalerType) {
+			return marshalerEncoder
+		}
+		if t.Kind() != reflect.Ptr && allowAddr && reflect.PtrTo(t).Implements(textMarshalerType) {
+			return condAddrEncoder(addrTextMarshalerEncoder, newTypeEncoder(t, false))
+		}
+		if t.Implements(textMarshalerType) {
+			return textMarshalerEncoder
+		}
 	}
-	dataType := reflect.TypeOf(data).Kind()
-	switch dataType {
-	case reflect.Int, reflect.Int64:
-		return encodeInt(data)
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return interfaceEncoder
+	case reflect.Ptr:
+		return newPtrEncoder(t)
+	case reflect.Bool:
+		return boolEncoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uintEncoder
 	case reflect.String:
-		return encodeString(data)
+		return stringEncoder
 	case reflect.Slice:
-		if reflect.TypeOf(data) == reflect.TypeOf([]byte(nil)) {
-			return encodeString(data)
+		if t == rawMessageType {
+			return rawEncoder
 		}
-		return encodeList(data)
+		if t.Elem().Kind() == reflect.Uint8 {
+			return bytesEncoder
+		}
+		return newListEncoder(t)
 	case reflect.Array:
-		return encodeList(data)
+		if t.Elem().Kind() == reflect.Uint8 {
+			return byteArrayEncoder
+		}
+		return newListEncoder(t)
 	case reflect.Map:
-		return encodeDict(data)
+		return newDictEncoder(t)
+	case reflect.Struct:
+		return newStructEncoder(t)
 	}
+	return unsupportedTypeEncoder
+}
 
-	return nil, errors.New("unsupported type")
+// condAddrEncoder uses addrEncoder for addressable values and elseEncoder
+// for the rest.
+func condAddrEncoder(addrEncoder, elseEncoder encoderFunc) encoderFunc {
+	return func(w tokenWriter, value reflect.Value) error {
+		if value.CanAddr() {
+			return addrEncoder(w, value)
+		}
+		return elseEncoder(w, value)
+	}
 }
 
-func encodeInt(data interface{}) ([]byte, error) {
-	value, ok := data.(int64)
-	if !ok {
-		return nil, errors.New("invalid int provided")
+func interfaceEncoder(w tokenWriter, value reflect.Value) error {
+	if value.IsNil() {
+		return errors.New("no data to encode")
 	}
-	return []byte(fmt.Sprintf("i%de", value)), nil
+	element := value.Elem()
+	return typeEncoder(element.Type())(w, element)
 }
 
-func encodeString(data interface{}) ([]byte, error) {
-	var valueArray []uint8
-	var value string
-	ok := true
-	if reflect.TypeOf(data).Kind() != reflect.String {
-		valueArray, ok = data.([]uint8)
-		if !ok {
-			return nil, errors.New("invalid string provided")
+func newPtrEncoder(t reflect.Type) encoderFunc {
+	elementEncoder := typeEncoder(t.Elem())
+	return func(w tokenWriter, value reflect.Value) error {
+		if value.IsNil() {
+			return errors.New("no data to encode")
 		}
-		value = string(valueArray)
+		return elementEncoder(w, value.Elem())
+	}
+}
+
+func boolEncoder(w tokenWriter, value reflect.Value) error {
+	if value.Bool() {
+		writeInt(w, 1)
 	} else {
-		value, ok = data.(string)
+		writeInt(w, 0)
 	}
+	return nil
+}
+
+func intEncoder(w tokenWriter, value reflect.Value) error {
+	writeInt(w, value.Int())
+	return nil
+}
+
+func uintEncoder(w tokenWriter, value reflect.Value) error {
+	writeUint(w, value.Uint())
+	return nil
+}
+
+func stringEncoder(w tokenWriter, value reflect.Value) error {
+	writeString(w, value.String())
+	return nil
+}
 
-	if !ok {
-		return nil, errors.New("invalid string provided")
+func rawEncoder(w tokenWriter, value reflect.Value) error {
+	return writeRaw(w, value.Bytes())
+}
+
+func bytesEncoder(w tokenWriter, value reflect.Value) error {
+	writeBytes(w, value.Bytes())
+	return nil
+}
+
+func byteArrayEncoder(w tokenWriter, value reflect.Value) error {
+	writeLength(w, value.Len())
+	if value.CanAddr() {
+		_, _ = w.Write(value.Slice(0, value.Len()).Bytes())
+		return nil
+	}
+	for i := 0; i < value.Len(); i++ {
+		_ = w.WriteByte(byte(value.Index(i).Uint()))
 	}
-	return []byte(fmt.Sprintf("%d:%s", len(value), value)), nil
+	return nil
 }
 
-func encodeList(data interface{}) ([]byte, error) {
-	if reflect.TypeOf(data).Kind() != reflect.Slice {
-		return nil, errors.New("input data is not a list")
+func unsupportedTypeEncoder(w tokenWriter, value reflect.Value) error {
+	return fmt.Errorf("unsupported type %s", value.Type())
+}
+
+func writeInt(w tokenWriter, value int64) {
+	_ = w.WriteByte('i')
+	if value < 0 {
+		_ = w.WriteByte('-')
+		writeDecimal(w, uint64(-(value+1))+1)
+	} else {
+		writeDecimal(w, uint64(value))
 	}
-	var result []byte
-	result = append(result, 'l') // Start with 'l'
-	s := reflect.ValueOf(data)
+	_ = w.WriteByte('e')
+}
 
-	for i := 0; i < s.Len(); i++ {
-		elem := s.Index(i).Interface()
-		encodedElem, err := encode(elem)
-		if err != nil {
-			return nil, err
+func writeUint(w tokenWriter, value uint64) {
+	_ = w.WriteByte('i')
+	writeDecimal(w, value)
+	_ = w.WriteByte('e')
+}
+
+func writeLength(w tokenWriter, length int) {
+	writeDecimal(w, uint64(length))
+	_ = w.WriteByte(':')
+}
+
+// writeDecimal writes value byte by byte, since a scratch buffer passed to
+// the interface's Write would escape and cost an allocation per number.
+func writeDecimal(w tokenWriter, value uint64) {
+	var digits [20]byte
+	i := len(digits)
+	for {
+		i--
+		digits[i] = byte('0' + value%10)
+		value /= 10
+		if value == 0 {
+			break
 		}
-		result = append(result, encodedElem...)
 	}
-	result = append(result, 'e') // End with 'e'
-	return result, nil
+	for ; i < len(digits); i++ {
+		_ = w.WriteByte(digits[i])
+	}
 }
 
-func encodeDict(data interface{}) ([]byte, error) {
-	if reflect.TypeOf(data).Kind() != reflect.Map {
-		return nil, errors.New("input data is not a map")
-	}
+func writeString(w tokenWriter, value string) {
+	writeLength(w, len(value))
+	_, _ = w.WriteString(value)
+}
 
-	mappedData, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, errors.New("input data is not a map with string keys")
+func writeBytes(w tokenWriter, value []byte) {
+	writeLength(w, len(value))
+	_, _ = w.Write(value)
+}
+
+func newListEncoder(t reflect.Type) encoderFunc {
+	elementEncoder := typeEncoder(t.Elem())
+	return func(w tokenWriter, value reflect.Value) error {
+		_ = w.WriteByte('l')
+		for i := 0; i < value.Len(); i++ {
+			if err := elementEncoder(w, value.Index(i)); err != nil {
+				return err
+			}
+		}
+		_ = w.WriteByte('e')
+		return nil
 	}
+}
+
+// sortedKeys orders the entries of a map by key without moving them.
+type sortedKeys struct {
+	keys  []string
+	order []int
+}
 
-	var result []byte
-	result = append(result, 'd') // Start with 'd'
+func (s *sortedKeys) Len() int           { return len(s.order) }
+func (s *sortedKeys) Less(i, j int) bool { return s.keys[s.order[i]] < s.keys[s.order[j]] }
+func (s *sortedKeys) Swap(i, j int)      { s.order[i], s.order[j] = s.order[j], s.order[i] }
 
-	keys := make([]string, 0, len(mappedData))
-	for key := range mappedData {
-		keys = append(keys, key)
+func newDictEncoder(t reflect.Type) encoderFunc {
+	if t.Key().Kind() != reflect.String {
+		return func(w tokenWriter, value reflect.Value) error {
+			return errors.New("input data is not a map with string keys")
+		}
 	}
-	sort.Strings(keys) // Sort keys lexicographically
 
-	// Encode each key-value pair in sorted order
-	for _, key := range keys {
-		encodedKey, err := encodeString(key)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode key '%s': %w", key, err)
+	elementEncoder := typeEncoder(t.Elem())
+	valuesType := reflect.SliceOf(t.Elem())
+	return func(w tokenWriter, value reflect.Value) error {
+		// copy the entries out once, rather than boxing each key and value
+		n := value.Len()
+		sorted := sortedKeys{keys: make([]string, n), order: make([]int, n)}
+		values := reflect.MakeSlice(valuesType, n, n)
+		key := reflect.New(t.Key()).Elem()
+		iter := value.MapRange()
+		for i := 0; iter.Next(); i++ {
+			key.SetIterKey(iter)
+			sorted.keys[i] = key.String()
+			sorted.order[i] = i
+			values.Index(i).SetIterValue(iter)
 		}
-		result = append(result, encodedKey...)
+		sort.Sort(&sorted) // Sort keys lexicographically
 
-		encodedValue, err := encode(mappedData[key])
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode value for key '%s': %w", key, err)
+		_ = w.WriteByte('d')
+		for _, i := range sorted.order {
+			element := values.Index(i)
+			if isNilValue(element) {
+				continue
+			}
+			writeString(w, sorted.keys[i])
+			if err := elementEncoder(w, element); err != nil {
+				return fmt.Errorf("failed to encode value for key '%s': %w", sorted.keys[i], err)
+			}
 		}
-		result = append(result, encodedValue...)
+		_ = w.WriteByte('e')
+		return nil
 	}
-
-	result = append(result, 'e') // End with 'e'
-	return result, nil
 }
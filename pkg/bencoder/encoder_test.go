@@ -0,0 +1,98 @@
+package bencoder
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder := NewEncoder(&buffer)
+
+	values := []interface{}{
+		int64(1),
+		"spam",
+		map[string]interface{}{"foo": []interface{}{int64(2)}, "bar": []byte("eggs")},
+		struct {
+			Name   string `bencode:"name"`
+			Length int    `bencode:"length"`
+		}{Name: "file", Length: 10},
+	}
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+
+	want := "i1e4:spamd3:bar4:eggs3:fooli2eeed6:lengthi10e4:name4:filee"
+	if buffer.String() != want {
+		t.Errorf("Encode() got = %q, want %q", buffer.String(), want)
+	}
+}
+
+func TestEncoder_EncodeErrors(t *testing.T) {
+	if err := NewEncoder(failingWriter{}).Encode("spam"); err == nil {
+		t.Errorf("Encode() expected writer error")
+	}
+
+	var buffer bytes.Buffer
+	if err := NewEncoder(&buffer).Encode(map[int]string{1: "one"}); err == nil {
+		t.Errorf("Encode() expected error for non string keys")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "Int", data: 42, want: "i42e"},
+		{name: "Negative Int", data: int64(-7), want: "i-7e"},
+		{name: "Typed String Map", data: map[string]string{"b": "2", "a": "1"}, want: "d1:a1:11:b1:2e"},
+		{name: "Pointer To Struct", data: &struct {
+			Foo int64 `bencode:"foo"`
+		}{Foo: 1}, want: "d3:fooi1ee"},
+		{name: "Nil", data: nil, wantErr: true},
+		{name: "Unsupported", data: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	files := make([]map[string]interface{}, 100)
+	for i := range files {
+		files[i] = map[string]interface{}{"length": int64(i), "path": []string{"dir", "file"}}
+	}
+	info := map[string]interface{}{
+		"files":        files,
+		"name":         "bench",
+		"piece length": int64(16384),
+		"pieces":       bytes.Repeat([]byte{1}, 20*1000),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(info); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
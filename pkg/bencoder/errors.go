@@ -0,0 +1,103 @@
+package bencoder
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError describes malformed bencode and where it was found.
+type SyntaxError struct {
+	Offset   int64  // byte offset at which the error was detected
+	Expected string // what the decoder expected at Offset, if known
+	msg      string
+	err      error
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("%s at offset %d", e.msg, e.Offset)
+	}
+	return fmt.Sprintf("%s at offset %d, expected %s", e.msg, e.Offset, e.Expected)
+}
+
+// Unwrap returns the underlying cause, io.ErrUnexpectedEOF for truncated input.
+func (e *SyntaxError) Unwrap() error {
+	return e.err
+}
+
+// UnmarshalTypeError describes a bencoded value that could not be stored
+// in the Go value it was decoded into.
+type UnmarshalTypeError struct {
+	Value  string       // description of the bencoded value, e.g. "string" or "integer 300"
+	Type   reflect.Type // type of the Go value it could not be assigned to
+	Offset int64        // byte offset of the value in the input
+	Path   string       // key path to the value, e.g. info.files[3].length
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	message := fmt.Sprintf("cannot decode %s into Go value of type %s", e.Value, e.Type)
+	if e.Path != "" {
+		message += " at " + e.Path
+	}
+	return message
+}
+
+// UnmarshalerError reports an error returned by the UnmarshalBencode or
+// UnmarshalText method of the Go value a bencoded value was decoded into.
+type UnmarshalerError struct {
+	Type   reflect.Type // type whose method failed
+	Offset int64        // byte offset of the value in the input
+	Path   string       // key path to the value
+	Err    error
+}
+
+func (e *UnmarshalerError) Error() string {
+	message := fmt.Sprintf("cannot decode into Go value of type %s", e.Type)
+	if e.Path != "" {
+		message += " at " + e.Path
+	}
+	return message + ": " + e.Err.Error()
+}
+
+func (e *UnmarshalerError) Unwrap() error {
+	return e.Err
+}
+
+// joinPath appends inner, a key path within the value at outer, to outer.
+func joinPath(outer, inner string) string {
+	switch {
+	case outer == "":
+		return inner
+	case inner == "":
+		return outer
+	case inner[0] == '[':
+		return outer + inner
+	}
+	return outer + "." + inner
+}
+
+// pathSegment is a dict key or, when key is empty and isIndex set, a list index.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func formatPath(path []pathSegment) string {
+	var builder strings.Builder
+	for _, segment := range path {
+		if segment.isIndex {
+			builder.WriteByte('[')
+			builder.WriteString(strconv.Itoa(segment.index))
+			builder.WriteByte(']')
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteByte('.')
+		}
+		builder.WriteString(segment.key)
+	}
+	return builder.String()
+}
@@ -0,0 +1,65 @@
+package bencoder
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantOffset int64
+		wantEOF    bool
+	}{
+		{name: "Bad Integer", data: "d3:fooi1x2ee", wantOffset: 7},
+		{name: "Bad Key", data: "di1ei2ee", wantOffset: 1},
+		{name: "Truncated", data: "d3:fooli1e", wantOffset: 10, wantEOF: true},
+		{name: "Bad Length", data: "l1x:ae", wantOffset: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSimpleBencoder().Decode([]byte(tt.data))
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Decode() error = %v, want *SyntaxError", err)
+			}
+			if syntaxErr.Offset != tt.wantOffset {
+				t.Errorf("Offset got = %d, want %d", syntaxErr.Offset, tt.wantOffset)
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) != tt.wantEOF {
+				t.Errorf("errors.Is(err, io.ErrUnexpectedEOF) = %v, want %v", !tt.wantEOF, tt.wantEOF)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	type file struct {
+		Length int64 `bencode:"length"`
+	}
+	var target struct {
+		Info struct {
+			Files []file `bencode:"files"`
+		} `bencode:"info"`
+	}
+
+	data := []byte("d4:infod5:filesld6:lengthi1eed6:lengthi2eed6:lengthi3eed6:length3:badeeee")
+	err := NewSimpleBencoder().Unmarshal(data, &target)
+
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+	}
+	if typeErr.Path != "info.files[3].length" {
+		t.Errorf("Path got = %q, want %q", typeErr.Path, "info.files[3].length")
+	}
+	if typeErr.Type != reflect.TypeOf(int64(0)) || typeErr.Value != "string" || typeErr.Offset != 64 {
+		t.Errorf("UnmarshalTypeError got = %+v", typeErr)
+	}
+	if len(target.Info.Files) != 4 || target.Info.Files[2].Length != 3 {
+		t.Errorf("Unmarshal() got = %+v", target)
+	}
+}
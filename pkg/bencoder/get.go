@@ -0,0 +1,204 @@
+package bencoder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ErrPathNotFound is wrapped by the error Get returns when a key or list
+// index on the path does not exist.
+var ErrPathNotFound = errors.New("path not found")
+
+// RawValue is a value found by Get: its exact encoding, sharing the memory
+// of the document it was found in, and its position there.
+type RawValue struct {
+	Raw    RawMessage
+	Offset int // position of the value's first byte
+	End    int // position just past the value's last byte
+}
+
+// Kind returns DictBegin, ListBegin, Integer or String for the kind of value.
+func (v RawValue) Kind() TokenKind {
+	switch {
+	case len(v.Raw) == 0:
+		return 0
+	case v.Raw[0] == 'd':
+		return DictBegin
+	case v.Raw[0] == 'l':
+		return ListBegin
+	case v.Raw[0] == 'i':
+		return Integer
+	}
+	return String
+}
+
+// Int returns the value of an integer.
+func (v RawValue) Int() (int64, error) {
+	if v.Kind() != Integer {
+		return 0, fmt.Errorf("%s is not an integer", v.describe())
+	}
+	digits := v.Raw[1 : len(v.Raw)-1]
+	value, ok := parseInt(digits, 64)
+	if !ok {
+		return 0, fmt.Errorf("integer %s out of range", digits)
+	}
+	return value, nil
+}
+
+// Bytes returns the contents of a byte string, sharing its memory.
+func (v RawValue) Bytes() ([]byte, error) {
+	if v.Kind() != String {
+		return nil, fmt.Errorf("%s is not a string", v.describe())
+	}
+	return v.Raw[bytes.IndexByte(v.Raw, ':')+1:], nil
+}
+
+func (v RawValue) describe() string {
+	if len(v.Raw) == 0 {
+		return "empty value"
+	}
+	return describeToken(v.Raw[0])
+}
+
+// sliceDecoder is a Decoder reading from a byte slice. They are pooled so
+// their scratch space is reused and a lookup does not allocate.
+type sliceDecoder struct {
+	Decoder
+	reader bytes.Reader
+}
+
+var sliceDecoderPool = sync.Pool{
+	New: func() interface{} {
+		s := new(sliceDecoder)
+		s.r = &s.reader
+		return s
+	},
+}
+
+func getSliceDecoder(data []byte) *sliceDecoder {
+	s := sliceDecoderPool.Get().(*sliceDecoder)
+	s.reader.Reset(data)
+	s.offset = 0
+	s.limits = DefaultLimits
+	s.valueStart = 0
+	s.depth = 0
+	s.items = 0
+	s.path = s.path[:0]
+	s.keyBytes = s.keyBytes[:0]
+	s.keyEnds = s.keyEnds[:0]
+	s.warnings = nil
+	return s
+}
+
+func putSliceDecoder(s *sliceDecoder) {
+	s.reader.Reset(nil)
+	sliceDecoderPool.Put(s)
+}
+
+// Get finds the value at path in a bencoded document without decoding the
+// rest of it. Each path element is a dict key or, inside a list, a decimal
+// index. Values before the one found are checked and skipped without being
+// built, and nothing after it is read, so data may hold trailing bytes.
+func Get(data []byte, path ...string) (RawValue, error) {
+	if len(data) == 0 {
+		return RawValue{}, errors.New("empty data")
+	}
+	d := getSliceDecoder(data)
+	defer putSliceDecoder(d)
+
+	b, _ := d.r.ReadByte()
+	d.offset++
+	for _, element := range path {
+		var found bool
+		var err error
+		switch b {
+		case 'd':
+			d.pushKey(element)
+			b, found, err = d.seekKey(element)
+		case 'l':
+			index, convErr := strconv.Atoi(element)
+			if convErr != nil || index < 0 {
+				return RawValue{}, fmt.Errorf("%w: %q is not a list index%s", ErrPathNotFound, element, d.atPath())
+			}
+			d.pushIndex(index)
+			b, found, err = d.seekIndex(index)
+		default:
+			return RawValue{}, fmt.Errorf("%w: cannot look up %q in %s%s", ErrPathNotFound, element, describeToken(b), d.atPath())
+		}
+		if err != nil {
+			return RawValue{}, err
+		}
+		if !found {
+			return RawValue{}, fmt.Errorf("%w: %s", ErrPathNotFound, formatPath(d.path))
+		}
+	}
+
+	start := int(d.offset) - 1
+	if err := d.skipValue(b); err != nil {
+		return RawValue{}, err
+	}
+	end := int(d.offset)
+	return RawValue{Raw: data[start:end:end], Offset: start, End: end}, nil
+}
+
+// seekKey reads a dict, whose 'd' has been consumed, up to the value of
+// key and returns its first byte.
+func (d *Decoder) seekKey(key string) (byte, bool, error) {
+	if err := d.enter(); err != nil {
+		return 0, false, err
+	}
+	keys := d.beginKeys()
+	for {
+		b, err := d.next("dictionary key or 'e'")
+		if err != nil || b == 'e' {
+			return 0, false, err
+		}
+		if err := d.countItem(); err != nil {
+			return 0, false, err
+		}
+		start := d.offset - 1
+		current, err := d.readKeyScratch(b)
+		if err != nil {
+			return 0, false, err
+		}
+		if err := d.addKey(keys, start, current); err != nil {
+			return 0, false, err
+		}
+		b, err = d.next("dictionary value")
+		if err != nil {
+			return 0, false, err
+		}
+		if string(current) == key {
+			return b, true, nil
+		}
+		if err := d.skipValue(b); err != nil {
+			return 0, false, err
+		}
+	}
+}
+
+// seekIndex reads a list, whose 'l' has been consumed, up to the element
+// at index and returns its first byte.
+func (d *Decoder) seekIndex(index int) (byte, bool, error) {
+	if err := d.enter(); err != nil {
+		return 0, false, err
+	}
+	for i := 0; ; i++ {
+		b, err := d.next("list element or 'e'")
+		if err != nil || b == 'e' {
+			return 0, false, err
+		}
+		if err := d.countItem(); err != nil {
+			return 0, false, err
+		}
+		if i == index {
+			return b, true, nil
+		}
+		if err := d.skipValue(b); err != nil {
+			return 0, false, err
+		}
+	}
+}
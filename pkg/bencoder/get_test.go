@@ -0,0 +1,115 @@
+package bencoder
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	data := []byte("d8:announce3:url4:infod5:filesld6:lengthi5e4:pathl1:aeed6:lengthi7e4:pathl1:beee4:name4:demo12:piece lengthi16384eee")
+	tests := []struct {
+		name       string
+		path       []string
+		want       string
+		wantOffset int
+		wantErr    error
+	}{
+		{
+			name:       "Whole Document",
+			want:       string(data),
+			wantOffset: 0,
+		},
+		{
+			name:       "Top Level Key",
+			path:       []string{"announce"},
+			want:       "3:url",
+			wantOffset: 11,
+		},
+		{
+			name:       "Nested Key",
+			path:       []string{"info", "piece length"},
+			want:       "i16384e",
+			wantOffset: 107,
+		},
+		{
+			name:       "List Index",
+			path:       []string{"info", "files", "1", "length"},
+			want:       "i7e",
+			wantOffset: 64,
+		},
+		{
+			name:    "Missing Key",
+			path:    []string{"info", "private"},
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "Index Out Of Range",
+			path:    []string{"info", "files", "2"},
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "Key In List",
+			path:    []string{"info", "files", "length"},
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "Key In String",
+			path:    []string{"announce", "url"},
+			wantErr: ErrPathNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Get(data, tt.path...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if string(got.Raw) != tt.want {
+				t.Errorf("Get() = %s, want %s", got.Raw, tt.want)
+			}
+			if got.Offset != tt.wantOffset || got.End != tt.wantOffset+len(tt.want) {
+				t.Errorf("Get() offsets = %d-%d, want %d-%d", got.Offset, got.End, tt.wantOffset, tt.wantOffset+len(tt.want))
+			}
+			if string(data[got.Offset:got.End]) != tt.want {
+				t.Errorf("data[Offset:End] = %s, want %s", data[got.Offset:got.End], tt.want)
+			}
+		})
+	}
+}
+
+func TestGet_Truncated(t *testing.T) {
+	_, err := Get([]byte("d4:infod4:name"), "info", "name")
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Get() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestRawValue_Accessors(t *testing.T) {
+	data := []byte("d6:lengthi-42e4:name4:demoe")
+
+	length, err := Get(data, "length")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := length.Int(); err != nil || value != -42 {
+		t.Errorf("Int() = %d, %v, want -42", value, err)
+	}
+	if _, err := length.Bytes(); err == nil {
+		t.Error("Bytes() of an integer succeeded")
+	}
+
+	name, err := Get(data, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := name.Bytes(); err != nil || string(value) != "demo" {
+		t.Errorf("Bytes() = %q, %v, want demo", value, err)
+	}
+	if name.Kind() != String || length.Kind() != Integer {
+		t.Errorf("Kind() = %v, %v", name.Kind(), length.Kind())
+	}
+}
@@ -0,0 +1,297 @@
+package bencoder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// BinaryEncoding selects how ToJSON writes byte strings that are not UTF-8.
+type BinaryEncoding int
+
+const (
+	BinaryHex BinaryEncoding = iota
+	BinaryBase64
+)
+
+// The tags marking a byte string that is not UTF-8. As a value they are the
+// only key of an object, {"$hex": "00ff"}; as a dict key they are a prefix,
+// "$hex:00ff". Keys that really start with '$' get a second one.
+const (
+	hexTag    = "$hex"
+	base64Tag = "$base64"
+)
+
+// jsonFrame is a list or dict being written by ToJSON.
+type jsonFrame struct {
+	closing byte
+	empty   bool
+}
+
+// ToJSON converts a bencoded document to JSON. Dicts become objects with
+// their keys in the original order, lists become arrays, integers become
+// numbers of any size and UTF-8 byte strings become strings. Other byte
+// strings become tagged objects, so FromJSON gives back the same bencode.
+func ToJSON(data []byte, binary BinaryEncoding) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty data")
+	}
+
+	scanner := NewScanner(bytes.NewReader(data))
+	var out bytes.Buffer
+	var stack []jsonFrame
+	afterKey := false
+	for {
+		token, err := scanner.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Kind == End {
+			out.WriteByte(stack[len(stack)-1].closing)
+			stack = stack[:len(stack)-1]
+		} else {
+			// separate this element from the previous one
+			if afterKey {
+				afterKey = false
+			} else if len(stack) > 0 {
+				if !stack[len(stack)-1].empty {
+					out.WriteByte(',')
+				}
+				stack[len(stack)-1].empty = false
+			}
+
+			switch token.Kind {
+			case DictBegin:
+				out.WriteByte('{')
+				stack = append(stack, jsonFrame{closing: '}', empty: true})
+			case ListBegin:
+				out.WriteByte('[')
+				stack = append(stack, jsonFrame{closing: ']', empty: true})
+			case Key:
+				writeJSONString(&out, jsonKey(token.Value, binary))
+				out.WriteByte(':')
+				afterKey = true
+			case Integer:
+				out.Write(trimLeadingZeros(token.Value))
+			case String:
+				writeJSONBytes(&out, token.Value, binary)
+			}
+		}
+
+		if len(stack) == 0 {
+			break
+		}
+	}
+
+	if scanner.InputOffset() != int64(len(data)) {
+		return nil, &SyntaxError{Offset: scanner.InputOffset(), Expected: "end of input", msg: "trailing data after top-level value"}
+	}
+	return out.Bytes(), nil
+}
+
+// jsonKey returns the JSON object key for a dict key.
+func jsonKey(key []byte, binary BinaryEncoding) string {
+	switch {
+	case !utf8.Valid(key):
+		if binary == BinaryBase64 {
+			return base64Tag + ":" + base64.StdEncoding.EncodeToString(key)
+		}
+		return hexTag + ":" + hex.EncodeToString(key)
+	case len(key) > 0 && key[0] == '$':
+		return "$" + string(key)
+	}
+	return string(key)
+}
+
+// writeJSONBytes writes a byte string value.
+func writeJSONBytes(out *bytes.Buffer, value []byte, binary BinaryEncoding) {
+	if utf8.Valid(value) {
+		writeJSONString(out, string(value))
+		return
+	}
+	out.WriteByte('{')
+	if binary == BinaryBase64 {
+		writeJSONString(out, base64Tag)
+		out.WriteByte(':')
+		writeJSONString(out, base64.StdEncoding.EncodeToString(value))
+	} else {
+		writeJSONString(out, hexTag)
+		out.WriteByte(':')
+		writeJSONString(out, hex.EncodeToString(value))
+	}
+	out.WriteByte('}')
+}
+
+func writeJSONString(out *bytes.Buffer, value string) {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	out.Truncate(out.Len() - 1) // Encode ends the value with a newline
+}
+
+// trimLeadingZeros drops the leading zeros lenient bencode allows but JSON
+// numbers do not, keeping the sign.
+func trimLeadingZeros(digits []byte) []byte {
+	sign := 0
+	if digits[0] == '-' {
+		sign = 1
+	}
+	i := sign
+	for i < len(digits)-1 && digits[i] == '0' {
+		i++
+	}
+	if i == sign {
+		return digits
+	}
+	return append(digits[:sign:sign], digits[i:]...)
+}
+
+// FromJSON converts JSON written by ToJSON back to bencode. Object keys
+// are written in the order they appear. Floats, booleans and null have no
+// bencode equivalent and are rejected.
+func FromJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var out bytes.Buffer
+	if err := convertJSONValue(decoder, &out); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("trailing data after JSON value at offset %d", decoder.InputOffset())
+	}
+	return out.Bytes(), nil
+}
+
+func convertJSONValue(decoder *json.Decoder, out *bytes.Buffer) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '[' {
+			out.WriteByte('l')
+			for decoder.More() {
+				if err := convertJSONValue(decoder, out); err != nil {
+					return err
+				}
+			}
+			_, _ = decoder.Token() // ']'
+			out.WriteByte('e')
+			return nil
+		}
+		return convertJSONObject(decoder, out)
+	case string:
+		writeString(out, value)
+		return nil
+	case json.Number:
+		digits := value.String()
+		if !isInteger([]byte(digits)) {
+			return fmt.Errorf("number %s is not an integer at offset %d", digits, decoder.InputOffset())
+		}
+		out.WriteByte('i')
+		out.WriteString(digits)
+		out.WriteByte('e')
+		return nil
+	case nil:
+		return fmt.Errorf("JSON null has no bencode equivalent at offset %d", decoder.InputOffset())
+	}
+	return fmt.Errorf("JSON %v has no bencode equivalent at offset %d", token, decoder.InputOffset())
+}
+
+// convertJSONObject converts an object, whose '{' has been read, to either
+// a dict or a tagged byte string. Keys must be unique, as in a dict.
+func convertJSONObject(decoder *json.Decoder, out *bytes.Buffer) error {
+	out.WriteByte('d')
+	seen := map[string]bool{}
+	for i := 0; decoder.More(); i++ {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		if i == 0 && (key == hexTag || key == base64Tag) {
+			// a tagged byte string rather than a dict
+			out.Truncate(out.Len() - 1)
+			return convertTaggedBytes(decoder, out, key)
+		}
+		name, err := bencodeKey(key)
+		if err != nil {
+			return err
+		}
+		if seen[string(name)] {
+			return fmt.Errorf("duplicate key %q at offset %d", key, decoder.InputOffset())
+		}
+		seen[string(name)] = true
+		writeBytes(out, name)
+		if err := convertJSONValue(decoder, out); err != nil {
+			return err
+		}
+	}
+	_, _ = decoder.Token() // '}'
+	out.WriteByte('e')
+	return nil
+}
+
+func convertTaggedBytes(decoder *json.Decoder, out *bytes.Buffer, tag string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	text, ok := token.(string)
+	if !ok {
+		return fmt.Errorf("%s value must be a string at offset %d", tag, decoder.InputOffset())
+	}
+	value, err := decodeBinary(tag, text)
+	if err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("%s object must have a single key at offset %d", tag, decoder.InputOffset())
+	}
+	_, _ = decoder.Token() // '}'
+	writeBytes(out, value)
+	return nil
+}
+
+// bencodeKey reverses jsonKey.
+func bencodeKey(key string) ([]byte, error) {
+	if !strings.HasPrefix(key, "$") {
+		return []byte(key), nil
+	}
+	if strings.HasPrefix(key, "$$") {
+		return []byte(key[1:]), nil
+	}
+	tag, text, found := strings.Cut(key, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid key %q, keys starting with '$' must be escaped as '$$'", key)
+	}
+	return decodeBinary(tag, text)
+}
+
+func decodeBinary(tag, text string) ([]byte, error) {
+	var value []byte
+	var err error
+	switch tag {
+	case hexTag:
+		value, err = hex.DecodeString(text)
+	case base64Tag:
+		value, err = base64.StdEncoding.DecodeString(text)
+	default:
+		return nil, fmt.Errorf("unknown binary tag %q", tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value: %w", tag, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,143 @@
+package bencoder
+
+import (
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		binary  BinaryEncoding
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Scalars",
+			data: "li-3e4:spami123456789012345678901234567890ee",
+			want: `[-3,"spam",123456789012345678901234567890]`,
+		},
+		{
+			name: "Dict Keeps Key Order",
+			data: "d1:bi1e1:ali2eee",
+			want: `{"b":1,"a":[2]}`,
+		},
+		{
+			name: "Binary Value As Hex",
+			data: "d6:pieces3:\x00\xff\x10e",
+			want: `{"pieces":{"$hex":"00ff10"}}`,
+		},
+		{
+			name:   "Binary Value As Base64",
+			data:   "3:\x00\xff\x10",
+			binary: BinaryBase64,
+			want:   `{"$base64":"AP8Q"}`,
+		},
+		{
+			name: "Binary And Dollar Keys",
+			data: "d2:\xab\xcdi1e4:$hexi2ee",
+			want: `{"$hex:abcd":1,"$$hex":2}`,
+		},
+		{
+			name: "Empty Containers",
+			data: "ldele0:e",
+			want: `[{},[],""]`,
+		},
+		{
+			name: "Leading Zeros Dropped",
+			data: "i-007e",
+			want: `-7`,
+		},
+		{
+			name:    "Trailing Data",
+			data:    "i1ei2e",
+			wantErr: true,
+		},
+		{
+			name:    "Truncated",
+			data:    "d3:foo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON([]byte(tt.data), tt.binary)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Nested",
+			json: `{"announce": "url", "info": {"length": 5, "pieces": {"$hex": "00ff"}}}`,
+			want: "d8:announce3:url4:infod6:lengthi5e6:pieces2:\x00\xffee",
+		},
+		{
+			name: "Binary Key",
+			json: `{"$base64:q80=": [1, "$$"], "$$x": {}}`,
+			want: "d2:\xab\xcdli1e2:$$e2:$xdee",
+		},
+		{
+			name: "Big Integer",
+			json: `123456789012345678901234567890`,
+			want: "i123456789012345678901234567890e",
+		},
+		{name: "Float", json: `1.5`, wantErr: true},
+		{name: "Bool", json: `[true]`, wantErr: true},
+		{name: "Null", json: `{"a": null}`, wantErr: true},
+		{name: "Unescaped Dollar Key", json: `{"a": 1, "$hex": "00"}`, wantErr: true},
+		{name: "Tag With Extra Key", json: `{"$hex": "00", "a": 1}`, wantErr: true},
+		{name: "Invalid Hex", json: `{"$hex": "0g"}`, wantErr: true},
+		{name: "Trailing Data", json: `1 2`, wantErr: true},
+		{name: "Duplicate Key", json: `{"a": 1, "b": 2, "a": 3}`, wantErr: true},
+		{name: "Duplicate Escaped Key", json: `{"$hex:61": 1, "a": 2}`, wantErr: true},
+		{name: "Same Key In Nested Dicts", json: `{"a": {"a": 1}}`, want: "d1:ad1:ai1eee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromJSON([]byte(tt.json))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("FromJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	documents := []string{
+		"d8:announce3:url4:infod5:filesld6:lengthi5e4:pathl1:aeee4:name4:demo6:pieces4:\x01\x02\xfe\xffee",
+		"d12:piece layersd2:\x00\x01d1:$i1eee12:\xe4\xbd\xa0\xe5\xa5\xbd\xe4\xb8\x96\xe7\x95\x8clee",
+		"i-9223372036854775809e",
+		"ld1:bi1e1:ai2eee",
+	}
+	for _, document := range documents {
+		for _, binary := range []BinaryEncoding{BinaryHex, BinaryBase64} {
+			converted, err := ToJSON([]byte(document), binary)
+			if err != nil {
+				t.Fatalf("ToJSON(%q) error = %v", document, err)
+			}
+			back, err := FromJSON(converted)
+			if err != nil {
+				t.Fatalf("FromJSON(%s) error = %v", converted, err)
+			}
+			if string(back) != document {
+				t.Errorf("round trip of %q via %s = %q", document, converted, back)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package bencoder
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is wrapped by every LimitError.
+var ErrLimitExceeded = errors.New("decode limit exceeded")
+
+// Limits bounds the resources a Decoder spends on a single top-level value
+// from an untrusted source. A zero field means no limit.
+type Limits struct {
+	MaxDepth        int   // nesting depth of lists and dicts
+	MaxStringLength int64 // length of any one byte string
+	MaxItems        int64 // list elements plus dict entries across the value
+	MaxInputSize    int64 // encoded size of the value in bytes
+}
+
+// DefaultLimits is used by NewDecoder. It is generous enough for large
+// metainfo files while stopping runaway nesting and allocations.
+var DefaultLimits = Limits{
+	MaxDepth:        256,
+	MaxStringLength: 256 << 20,
+	MaxItems:        16 << 20,
+	MaxInputSize:    1 << 30,
+}
+
+// LimitError reports which limit a value exceeded and where.
+type LimitError struct {
+	Limit  string // name of the Limits field
+	Max    int64  // configured maximum
+	Offset int64  // byte offset at which the limit was crossed
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s of %d exceeded at offset %d", e.Limit, e.Max, e.Offset)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// SetLimits replaces the limits applied to each value decoded from now on.
+func (d *Decoder) SetLimits(limits Limits) {
+	d.limits = limits
+}
+
+// enter is called when a list or dict starts and leave when it ends.
+func (d *Decoder) enter() error {
+	d.depth++
+	if d.limits.MaxDepth > 0 && d.depth > d.limits.MaxDepth {
+		return &LimitError{Limit: "MaxDepth", Max: int64(d.limits.MaxDepth), Offset: d.offset - 1}
+	}
+	return nil
+}
+
+func (d *Decoder) leave() {
+	d.depth--
+}
+
+// countItem is called for each list element and dict entry.
+func (d *Decoder) countItem() error {
+	d.items++
+	if d.limits.MaxItems > 0 && d.items > d.limits.MaxItems {
+		return &LimitError{Limit: "MaxItems", Max: d.limits.MaxItems, Offset: d.offset - 1}
+	}
+	return nil
+}
+
+// checkSize is called before reading size more bytes of the current value.
+func (d *Decoder) checkSize(size int64) error {
+	if d.limits.MaxInputSize > 0 && d.offset+size-d.valueStart > d.limits.MaxInputSize {
+		return &LimitError{Limit: "MaxInputSize", Max: d.limits.MaxInputSize, Offset: d.offset}
+	}
+	return nil
+}
+
+// checkStringLength is called with the declared length of a string at offset.
+func (d *Decoder) checkStringLength(offset int64, length int) error {
+	if d.limits.MaxStringLength > 0 && int64(length) > d.limits.MaxStringLength {
+		return &LimitError{Limit: "MaxStringLength", Max: d.limits.MaxStringLength, Offset: offset}
+	}
+	return d.checkSize(int64(length))
+}
@@ -0,0 +1,108 @@
+package bencoder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoder_Limits(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		limits    Limits
+		wantLimit string
+	}{
+		{name: "Depth", data: "llllleeeee", limits: Limits{MaxDepth: 4}, wantLimit: "MaxDepth"},
+		{name: "Depth Within Limit", data: "lllleeee", limits: Limits{MaxDepth: 4}},
+		{name: "String Length", data: "d3:key5:valuee", limits: Limits{MaxStringLength: 4}, wantLimit: "MaxStringLength"},
+		{name: "Items", data: "li1ei2ed1:ai3eee", limits: Limits{MaxItems: 3}, wantLimit: "MaxItems"},
+		{name: "Input Size", data: "l4:spam4:eggse", limits: Limits{MaxInputSize: 10}, wantLimit: "MaxInputSize"},
+		{name: "Huge Declared Length", data: "99999999999:x", limits: Limits{MaxInputSize: 1 << 20}, wantLimit: "MaxInputSize"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := NewDecoder(strings.NewReader(tt.data))
+			decoder.SetLimits(tt.limits)
+
+			var got interface{}
+			err := decoder.Decode(&got)
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) || !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("Decode() error = %v, want *LimitError", err)
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("Limit got = %s, want %s", limitErr.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestDecoder_LimitsArePerValue(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("li1ei2eeli3ei4ee"))
+	decoder.SetLimits(Limits{MaxItems: 2, MaxInputSize: 8})
+
+	for i := 0; i < 2; i++ {
+		var got []int
+		if err := decoder.Decode(&got); err != nil {
+			t.Fatalf("Decode() %d error = %v", i, err)
+		}
+	}
+}
+
+func TestDecoder_HugeLengthDoesNotAllocate(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("4000000000:short"))
+	decoder.SetLimits(Limits{})
+
+	var got []byte
+	if err := decoder.Decode(&got); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Decode() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	seeds := []string{
+		"i42e", "i-0e", "4:spam", "le", "de", "li1e4:spame",
+		"d3:bar4:spam3:fooi42ee", "d4:infod6:lengthi5e4:name1:a12:piece lengthi1e6:pieces0:ee",
+		"d3:fooi1e3:bari2ee", "l", "d3:foo", "10:short", "i9999999999999999999999e",
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bencoder := NewSimpleBencoder()
+		value, err := bencoder.Decode(data)
+		if err == nil {
+			// anything that decodes must survive a round trip
+			encoded, err := bencoder.Encode(value)
+			if err != nil {
+				t.Fatalf("Encode() error = %v for %q", err, data)
+			}
+			again, err := bencoder.Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode() of re-encoded %q error = %v", encoded, err)
+			}
+			if encodedAgain, _ := bencoder.Encode(again); !bytes.Equal(encoded, encodedAgain) {
+				t.Fatalf("round trip mismatch %q != %q", encoded, encodedAgain)
+			}
+		}
+
+		var target struct {
+			Announce string                `bencode:"announce"`
+			Info     map[string]RawMessage `bencode:"info"`
+			List     []int64               `bencode:"list"`
+			Any      interface{}           `bencode:"any"`
+		}
+		_ = bencoder.Unmarshal(data, &target)
+		_, _ = DecodeLenient(data, &target)
+	})
+}
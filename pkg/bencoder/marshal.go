@@ -1,70 +1,231 @@
 package bencoder
 
 import (
-	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 )
 
-func mapStructToMap(target interface{}) (map[string]interface{}, error) {
-	reflectValue := reflect.ValueOf(target)
-	if reflectValue.Kind() == reflect.Ptr {
-		reflectValue = reflectValue.Elem()
+// structField is a struct field written to a dict under its key. index is
+// the path to the field through any embedded structs.
+type structField struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	required  bool
+	// extra marks the field collecting the keys no other field decodes
+	extra bool
+}
+
+// tagOptions is the part of a bencode tag after the key name.
+type tagOptions string
+
+// parseTag splits a bencode tag into the key name and its options.
+func parseTag(tag string) (string, tagOptions) {
+	name, options, _ := strings.Cut(tag, ",")
+	return name, tagOptions(options)
+}
+
+// Contains reports whether the comma separated options include option.
+func (o tagOptions) Contains(option string) bool {
+	s := string(o)
+	for s != "" {
+		var current string
+		current, s, _ = strings.Cut(s, ",")
+		if current == option {
+			return true
+		}
 	}
-	if reflectValue.Kind() != reflect.Struct {
-		return nil, errors.New("struct or pointer to struct required")
+	return false
+}
+
+// structFields returns the exported fields of t sorted by key, which is
+// the order bencode requires for dict keys. Keys come from the bencode tag
+// or default to the field name; fields tagged "-" are skipped. Fields of
+// untagged embedded structs are promoted; when keys collide the shallowest
+// field wins and equally deep ones cancel out, as in encoding/json.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	collectFields(t, nil, map[reflect.Type]bool{}, &fields)
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		return len(fields[i].index) < len(fields[j].index)
+	})
+
+	result := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if j == i+1 || len(fields[i+1].index) > len(fields[i].index) {
+			result = append(result, fields[i])
+		}
+		i = j
 	}
+	return result
+}
 
-	mappedData := make(map[string]interface{})
-	reflectType := reflectValue.Type()
+func collectFields(t reflect.Type, parent []int, visiting map[reflect.Type]bool, fields *[]structField) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
 
-	for i := 0; i < reflectType.NumField(); i++ {
-		field := reflectType.Field(i)
-		fieldValue := reflectValue.Field(i)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		bencodeTag := field.Tag.Get("bencode")
+		if bencodeTag == "-" {
+			continue
+		}
+		name, options := parseTag(bencodeTag)
+		index := append(append([]int(nil), parent...), i)
 
-		bencodeTag, exists := field.Tag.Lookup("bencode")
-		if !exists || !fieldValue.CanInterface() {
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectFields(embedded, index, visiting, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
 			continue
 		}
+		if options.Contains("extra") {
+			// extra fields have no key of their own; the empty name makes
+			// equally deep ones cancel out like other colliding fields
+			name = ""
+		} else if name == "" {
+			name = field.Name
+		}
+		*fields = append(*fields, structField{
+			name:      name,
+			index:     index,
+			typ:       field.Type,
+			omitEmpty: options.Contains("omitempty"),
+			required:  options.Contains("required"),
+			extra:     options.Contains("extra"),
+		})
+	}
+}
 
-		value, err := mapFieldValue(fieldValue)
-		if err != nil {
-			return nil, err
+// fieldByIndex is reflect.Value.FieldByIndex, reporting false when the path
+// goes through a nil embedded struct pointer.
+func fieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
 		}
-		mappedData[bencodeTag] = value
+		value = value.Field(x)
 	}
+	return value, true
+}
 
-	return mappedData, nil
+// isNilValue reports whether value is a nil pointer or interface, which
+// Marshal treats as an absent dict entry.
+func isNilValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
 }
 
-func mapFieldValue(fieldValue reflect.Value) (interface{}, error) {
-	switch fieldValue.Kind() {
-	case reflect.Struct:
-		return mapStructToMap(fieldValue.Interface())
-	case reflect.Ptr:
-		if fieldValue.Elem().Kind() == reflect.Struct {
-			return mapStructToMap(fieldValue.Interface())
-		}
-		return fieldValue.Interface(), nil
-	case reflect.Slice, reflect.Array:
-		return mapSliceOrArray(fieldValue)
-	default:
-		return fieldValue.Interface(), nil
+// isEmptyValue reports whether value is left out by the omitempty option.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() == 0
 	}
+	return isNilValue(value)
 }
 
-func mapSliceOrArray(sliceValue reflect.Value) ([]interface{}, error) {
-	length := sliceValue.Len()
-	result := make([]interface{}, 0, length)
+// newStructEncoder builds the encoder writing a struct as a dict of its
+// fields, merged in key order with the entries of its extra field.
+func newStructEncoder(t reflect.Type) encoderFunc {
+	info := cachedStructInfo(t)
+	if info.extraErr != nil {
+		return func(w tokenWriter, value reflect.Value) error {
+			return info.extraErr
+		}
+	}
+	encoders := make([]encoderFunc, len(info.fields))
+	for i, field := range info.fields {
+		encoders[i] = typeEncoder(field.typ)
+	}
 
-	for i := 0; i < length; i++ {
-		elemValue := sliceValue.Index(i)
+	return func(w tokenWriter, value reflect.Value) error {
+		extra, extraKeys := info.extraEntries(value)
+		j := 0
+		_ = w.WriteByte('d')
+		for i, field := range info.fields {
+			for ; j < len(extraKeys) && extraKeys[j] < field.name; j++ {
+				if err := writeExtra(w, extra, extraKeys[j]); err != nil {
+					return err
+				}
+			}
+			fieldValue, ok := fieldByIndex(value, field.index)
+			if !ok || isNilValue(fieldValue) || (field.omitEmpty && isEmptyValue(fieldValue)) {
+				continue
+			}
+			writeString(w, field.name)
+			if err := encoders[i](w, fieldValue); err != nil {
+				return fmt.Errorf("failed to encode field '%s': %w", field.name, err)
+			}
+		}
+		for ; j < len(extraKeys); j++ {
+			if err := writeExtra(w, extra, extraKeys[j]); err != nil {
+				return err
+			}
+		}
+		_ = w.WriteByte('e')
+		return nil
+	}
+}
 
-		value, err := mapFieldValue(elemValue)
-		if err != nil {
-			return nil, err
+// extraEntries returns the extra field of value and its keys in sorted
+// order, leaving out keys a struct field is written under.
+func (info *structInfo) extraEntries(value reflect.Value) (map[string]RawMessage, []string) {
+	if info.extra == nil {
+		return nil, nil
+	}
+	extraValue, ok := fieldByIndex(value, info.extra.index)
+	if !ok || extraValue.Len() == 0 {
+		return nil, nil
+	}
+	extra := extraValue.Convert(extraMapType).Interface().(map[string]RawMessage)
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		if _, modelled := info.byName[key]; !modelled {
+			keys = append(keys, key)
 		}
-		result = append(result, value)
 	}
+	sort.Strings(keys)
+	return extra, keys
+}
 
-	return result, nil
+func writeExtra(w tokenWriter, extra map[string]RawMessage, key string) error {
+	writeString(w, key)
+	if err := writeRaw(w, extra[key]); err != nil {
+		return fmt.Errorf("failed to encode value for key '%s': %w", key, err)
+	}
+	return nil
 }
@@ -0,0 +1,134 @@
+package bencoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tagOptionsTarget struct {
+	Name     string   `bencode:"name,required"`
+	Length   int64    `bencode:"length,omitempty"`
+	Files    []string `bencode:"files,omitempty"`
+	Private  bool     `bencode:",omitempty"`
+	Ignored  string   `bencode:"-"`
+	Dash     string   `bencode:"-,"`
+	Untagged string
+	internal string
+}
+
+func TestMarshal_TagOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		target tagOptionsTarget
+		want   string
+	}{
+		{
+			name:   "Empty Fields Omitted",
+			target: tagOptionsTarget{Name: "single", Ignored: "x", internal: "y"},
+			want:   "d1:-0:8:Untagged0:4:name6:singlee",
+		},
+		{
+			name:   "Set Fields Kept",
+			target: tagOptionsTarget{Name: "multi", Files: []string{"a"}, Length: 1, Private: true, Dash: "d", Untagged: "u"},
+			want:   "d1:-1:d7:Privatei1e8:Untagged1:u5:filesl1:ae6:lengthi1e4:name5:multie",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.target)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_TagOptions(t *testing.T) {
+	var got tagOptionsTarget
+	data := []byte("d7:Ignored1:x7:Privatei1e8:Untagged1:u8:internal1:ie")
+	err := NewSimpleBencoder().Unmarshal(data, &got)
+	if err == nil || !strings.Contains(err.Error(), `missing required key "name"`) {
+		t.Fatalf("Unmarshal() error = %v, want missing required key", err)
+	}
+	if got.Ignored != "" || got.internal != "" {
+		t.Errorf("Unmarshal() set ignored fields: %+v", got)
+	}
+	if !got.Private || got.Untagged != "u" {
+		t.Errorf("Unmarshal() got = %+v", got)
+	}
+
+	if err := NewSimpleBencoder().Unmarshal([]byte("d4:name1:xe"), &got); err != nil {
+		t.Errorf("Unmarshal() error = %v", err)
+	}
+}
+
+type extraTarget struct {
+	Length int64                 `bencode:"length"`
+	Name   string                `bencode:"name,omitempty"`
+	Extra  map[string]RawMessage `bencode:",extra"`
+}
+
+func TestExtra_RoundTrip(t *testing.T) {
+	data := []byte("d1:ali1ee6:lengthi5e4:meta4:spam4:name4:demo1:zd1:xi0eee")
+
+	var got extraTarget
+	if err := NewSimpleBencoder().Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := extraTarget{
+		Length: 5,
+		Name:   "demo",
+		Extra: map[string]RawMessage{
+			"a":    RawMessage("li1ee"),
+			"meta": RawMessage("4:spam"),
+			"z":    RawMessage("d1:xi0ee"),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() got = %+v, want %+v", got, want)
+	}
+
+	encoded, err := Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(encoded) != string(data) {
+		t.Errorf("Marshal() got = %q, want %q", encoded, data)
+	}
+}
+
+func TestExtra_FieldsWin(t *testing.T) {
+	target := extraTarget{
+		Length: 1,
+		Extra:  map[string]RawMessage{"length": RawMessage("i2e"), "name": RawMessage("1:x")},
+	}
+	got, err := Marshal(target)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// name is modelled, so its extra entry is dropped even though the field is empty
+	if want := "d6:lengthi1ee"; string(got) != want {
+		t.Errorf("Marshal() got = %q, want %q", got, want)
+	}
+
+	target.Extra = map[string]RawMessage{"broken": nil}
+	if _, err := Marshal(target); err == nil {
+		t.Error("Marshal() of an empty extra value succeeded")
+	}
+}
+
+func TestExtra_InvalidType(t *testing.T) {
+	var target struct {
+		Extra map[string]string `bencode:",extra"`
+	}
+	if err := NewSimpleBencoder().Unmarshal([]byte("d1:a1:be"), &target); err == nil {
+		t.Error("Unmarshal() into a map[string]string extra field succeeded")
+	}
+	if _, err := Marshal(target); err == nil {
+		t.Error("Marshal() of a map[string]string extra field succeeded")
+	}
+}
@@ -0,0 +1,139 @@
+package bencoder
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Marshaler is implemented by types that produce their own bencoding. The
+// returned bytes must hold exactly one valid bencoded value.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that decode their own bencoded
+// representation. The input is the exact encoding of a single value and
+// must be copied if it is kept after returning.
+type Unmarshaler interface {
+	UnmarshalBencode(data []byte) error
+}
+
+var (
+	marshalerType       = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func marshalerEncoder(w tokenWriter, value reflect.Value) error {
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return errors.New("no data to encode")
+	}
+	data, err := value.Interface().(Marshaler).MarshalBencode()
+	if err != nil {
+		return err
+	}
+	if err := checkValid(data); err != nil {
+		return fmt.Errorf("invalid output from MarshalBencode of %s: %w", value.Type(), err)
+	}
+	_, _ = w.Write(data)
+	return nil
+}
+
+// addrMarshalerEncoder calls a MarshalBencode method with a pointer receiver.
+func addrMarshalerEncoder(w tokenWriter, value reflect.Value) error {
+	return marshalerEncoder(w, value.Addr())
+}
+
+// textMarshalerEncoder writes the output of MarshalText as a byte string.
+func textMarshalerEncoder(w tokenWriter, value reflect.Value) error {
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return errors.New("no data to encode")
+	}
+	text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return err
+	}
+	writeBytes(w, text)
+	return nil
+}
+
+func addrTextMarshalerEncoder(w tokenWriter, value reflect.Value) error {
+	return textMarshalerEncoder(w, value.Addr())
+}
+
+// unmarshalerDecoder hands the exact encoding of the value to
+// UnmarshalBencode. An error from the method is saved, like a type
+// mismatch, and decoding carries on.
+func unmarshalerDecoder(d *Decoder, b byte, target reflect.Value) error {
+	start := d.offset - 1
+	raw, err := d.readRaw(b)
+	if err != nil {
+		return err
+	}
+	if err := target.Addr().Interface().(Unmarshaler).UnmarshalBencode(raw); err != nil {
+		d.unmarshalerError(err, target.Type(), start)
+	}
+	return nil
+}
+
+// newTextUnmarshalerDecoder passes byte strings to UnmarshalText and
+// decodes anything else with kindDecoder.
+func newTextUnmarshalerDecoder(kindDecoder decoderFunc) decoderFunc {
+	return func(d *Decoder, b byte, target reflect.Value) error {
+		if !isDigit(b) {
+			return kindDecoder(d, b, target)
+		}
+		start := d.offset - 1
+		text, err := d.readString(b)
+		if err != nil {
+			return err
+		}
+		if err := target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(text); err != nil {
+			d.unmarshalerError(err, target.Type(), start)
+		}
+		return nil
+	}
+}
+
+// unmarshalerError records the error of an unmarshal method called on the
+// value at offset. Errors that an UnmarshalBencode method got by decoding
+// its input in turn are moved to the place of that input in the document,
+// so nested custom types report the real path and offset.
+func (d *Decoder) unmarshalerError(err error, t reflect.Type, offset int64) {
+	path := formatPath(d.path)
+	switch e := err.(type) {
+	case *UnmarshalTypeError:
+		moved := *e
+		moved.Offset += offset
+		moved.Path = joinPath(path, e.Path)
+		d.saveError(&moved)
+	case *UnmarshalerError:
+		moved := *e
+		moved.Offset += offset
+		moved.Path = joinPath(path, e.Path)
+		d.saveError(&moved)
+	default:
+		d.saveError(&UnmarshalerError{Type: t, Offset: offset, Path: path, Err: err})
+	}
+}
+
+// checkValid reports an error unless data is exactly one bencoded value.
+func checkValid(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty data")
+	}
+	d := NewDecoder(bytes.NewReader(data))
+	b, _ := d.r.ReadByte()
+	d.offset++
+	if err := d.skipValue(b); err != nil {
+		return err
+	}
+	if d.offset != int64(len(data)) {
+		return fmt.Errorf("trailing data at offset %d", d.offset)
+	}
+	return nil
+}
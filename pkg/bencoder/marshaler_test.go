@@ -0,0 +1,151 @@
+package bencoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// compactPeers uses the 6 bytes per peer form trackers send in "peers".
+type compactPeers []compactPeer
+
+type compactPeer struct {
+	IP   net.IP
+	Port uint16
+}
+
+func (p compactPeers) MarshalBencode() ([]byte, error) {
+	data := make([]byte, 0, 6*len(p))
+	for _, peer := range p {
+		data = append(data, peer.IP.To4()...)
+		data = binary.BigEndian.AppendUint16(data, peer.Port)
+	}
+	return Marshal(data)
+}
+
+func (p *compactPeers) UnmarshalBencode(data []byte) error {
+	var raw []byte
+	if err := NewDecoder(bytes.NewReader(data)).Decode(&raw); err != nil {
+		return err
+	}
+	if len(raw)%6 != 0 {
+		return errors.New("compact peers length not a multiple of 6")
+	}
+	*p = (*p)[:0]
+	for i := 0; i < len(raw); i += 6 {
+		*p = append(*p, compactPeer{IP: net.IP(raw[i : i+4]), Port: binary.BigEndian.Uint16(raw[i+4 : i+6])})
+	}
+	return nil
+}
+
+// hash20 is a fixed size hash written as hex text.
+type hash20 [4]byte
+
+func (h hash20) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h[:])), nil
+}
+
+func (h *hash20) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != len(h) {
+		return errors.New("invalid hash length")
+	}
+	_, err := hex.Decode(h[:], text)
+	return err
+}
+
+type invalidMarshaler struct{}
+
+func (invalidMarshaler) MarshalBencode() ([]byte, error) {
+	return []byte("i1ei2e"), nil
+}
+
+type peersReply struct {
+	Peers   compactPeers      `bencode:"peers"`
+	Hashes  []hash20          `bencode:"hashes"`
+	Servers map[string]net.IP `bencode:"servers"`
+}
+
+func TestMarshaler_RoundTrip(t *testing.T) {
+	reply := peersReply{
+		Peers:   compactPeers{{IP: net.IPv4(10, 0, 0, 1).To4(), Port: 6881}},
+		Hashes:  []hash20{{0xde, 0xad, 0xbe, 0xef}},
+		Servers: map[string]net.IP{"dht": net.ParseIP("1.2.3.4")},
+	}
+
+	data, err := NewSimpleBencoder().Marshal(reply)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := "d6:hashesl8:deadbeefe5:peers6:\x0a\x00\x00\x01\x1a\xe17:serversd3:dht7:1.2.3.4ee"
+	if string(data) != want {
+		t.Errorf("Marshal() got = %q, want %q", data, want)
+	}
+
+	var got peersReply
+	if err := NewSimpleBencoder().Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, reply) {
+		t.Errorf("Unmarshal() got = %+v, want %+v", got, reply)
+	}
+}
+
+func TestMarshaler_InvalidOutput(t *testing.T) {
+	if _, err := Marshal([]invalidMarshaler{{}}); err == nil {
+		t.Errorf("Marshal() expected error for invalid MarshalBencode output")
+	}
+}
+
+// envelope decodes its input itself, so errors inside it come back from
+// UnmarshalBencode.
+type envelope struct {
+	Reply peersReply
+}
+
+func (e *envelope) UnmarshalBencode(data []byte) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(&e.Reply)
+}
+
+func TestUnmarshaler_ErrorsAreSaved(t *testing.T) {
+	data := []byte("d6:hashesl8:deadbeef3:bade5:peers6:\x0a\x00\x00\x01\x1a\xe1e")
+	var got peersReply
+	err := NewDecoder(bytes.NewReader(data)).Decode(&got)
+
+	var unmarshalerErr *UnmarshalerError
+	if !errors.As(err, &unmarshalerErr) {
+		t.Fatalf("Decode() error = %v, want an UnmarshalerError", err)
+	}
+	want := &UnmarshalerError{Type: reflect.TypeOf(hash20{}), Offset: 20, Path: "hashes[1]", Err: unmarshalerErr.Err}
+	if !reflect.DeepEqual(unmarshalerErr, want) || unmarshalerErr.Err.Error() != "invalid hash length" {
+		t.Errorf("Decode() error = %#v, want %#v", unmarshalerErr, want)
+	}
+	// decoding carried on past the bad hash
+	if len(got.Peers) != 1 || got.Peers[0].Port != 6881 || got.Hashes[0] != (hash20{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("Decode() got = %+v", got)
+	}
+
+	var peers peersReply
+	err = NewDecoder(bytes.NewReader([]byte("d5:peers5:abcdee"))).Decode(&peers)
+	if !errors.As(err, &unmarshalerErr) || unmarshalerErr.Path != "peers" || unmarshalerErr.Offset != 8 {
+		t.Errorf("Decode() error = %v, want an UnmarshalerError at peers", err)
+	}
+}
+
+func TestUnmarshaler_NestedErrorLocation(t *testing.T) {
+	var target struct {
+		Reply envelope `bencode:"reply"`
+	}
+	err := NewDecoder(bytes.NewReader([]byte("d5:replyd5:peersi1eee"))).Decode(&target)
+
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Decode() error = %v, want an UnmarshalTypeError", err)
+	}
+	if typeErr.Path != "reply.peers" || typeErr.Offset != 16 {
+		t.Errorf("Decode() error at %s offset %d, want reply.peers offset 16", typeErr.Path, typeErr.Offset)
+	}
+}
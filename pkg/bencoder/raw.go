@@ -0,0 +1,28 @@
+package bencoder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// RawMessage is a raw encoded bencode value. Unmarshal fills it with the
+// exact bytes of the value instead of decoding it, and Marshal writes it
+// back unchanged, which keeps hashes over the encoding stable.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
+// writeRaw writes raw after checking it holds exactly one value, as is
+// done for the output of MarshalBencode, so Marshal cannot produce what
+// the decoder would reject.
+func writeRaw(w tokenWriter, raw []byte) error {
+	if len(raw) == 0 {
+		return errors.New("empty raw message")
+	}
+	if err := checkValid(raw); err != nil {
+		return fmt.Errorf("invalid raw message: %w", err)
+	}
+	_, _ = w.Write(raw)
+	return nil
+}
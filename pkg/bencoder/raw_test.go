@@ -0,0 +1,69 @@
+package bencoder
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRawMessage_Unmarshal(t *testing.T) {
+	// keys are deliberately left unsorted to show the bytes are kept as-is
+	data := []byte("d4:infod4:name4:test6:lengthi5e7:privatei1ee5:filesld6:lengthi1eeee")
+	var target struct {
+		Info  RawMessage   `bencode:"info"`
+		Files []RawMessage `bencode:"files"`
+	}
+
+	if err := NewSimpleBencoder().Unmarshal(data, &target); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if want := "d4:name4:test6:lengthi5e7:privatei1ee"; string(target.Info) != want {
+		t.Errorf("Unmarshal() Info got = %q, want %q", target.Info, want)
+	}
+	if len(target.Files) != 1 || string(target.Files[0]) != "d6:lengthi1ee" {
+		t.Errorf("Unmarshal() Files got = %q", target.Files)
+	}
+}
+
+func TestRawMessage_Marshal(t *testing.T) {
+	target := struct {
+		Info RawMessage `bencode:"info"`
+		Name string     `bencode:"name"`
+	}{
+		Info: RawMessage("d1:bi1e1:ai2ee"),
+		Name: "x",
+	}
+
+	got, err := NewSimpleBencoder().Marshal(target)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := []byte("d4:infod1:bi1e1:ai2ee4:name1:xe"); !bytes.Equal(got, want) {
+		t.Errorf("Marshal() got = %q, want %q", got, want)
+	}
+
+	target.Info = nil
+	if _, err := NewSimpleBencoder().Marshal(target); err == nil {
+		t.Errorf("Marshal() expected error for empty raw message")
+	}
+
+	for _, raw := range []string{"d1:a", "i1ei2e", "x", "3:ab"} {
+		target.Info = RawMessage(raw)
+		if got, err := NewSimpleBencoder().Marshal(target); err == nil {
+			t.Errorf("Marshal() of raw message %q = %q, expected an error", raw, got)
+		}
+	}
+}
+
+func TestRawMessage_Decoder(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader([]byte("li1e3:fooe4:spam")))
+	var first, second RawMessage
+	if err := decoder.Decode(&first); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if err := decoder.Decode(&second); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if string(first) != "li1e3:fooe" || string(second) != "4:spam" {
+		t.Errorf("Decode() got = %q, %q", first, second)
+	}
+}
@@ -0,0 +1,257 @@
+package bencoder
+
+import (
+	"io"
+	"strconv"
+)
+
+// TokenKind identifies the kind of a Token.
+type TokenKind int
+
+const (
+	DictBegin TokenKind = iota + 1
+	ListBegin
+	End
+	Key
+	Integer
+	String
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case DictBegin:
+		return "DictBegin"
+	case ListBegin:
+		return "ListBegin"
+	case End:
+		return "End"
+	case Key:
+		return "Key"
+	case Integer:
+		return "Integer"
+	case String:
+		return "String"
+	}
+	return "TokenKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Token is a single element of a bencoded document: the start of a dict or
+// list, the end of one, a dict key or a scalar value.
+type Token struct {
+	Kind TokenKind
+	// Offset is the position of the token's first byte in the input.
+	Offset int64
+	// Value holds the bytes of a Key or String and the decimal digits of an
+	// Integer. It is only valid until the next call to Next or Skip.
+	Value []byte
+}
+
+// Int returns the value of an Integer token, reporting false when the
+// token is not an integer or does not fit in an int64.
+func (t Token) Int() (int64, bool) {
+	if t.Kind != Integer {
+		return 0, false
+	}
+	return parseInt(t.Value, 64)
+}
+
+// scanFrame is a list or dict the Scanner is inside of.
+type scanFrame struct {
+	dict bool
+	keys *dictKeys
+	// afterKey is set between a dict key and its value
+	afterKey bool
+}
+
+// Scanner reads a bencoded stream one token at a time. It applies the same
+// checks and limits as Decoder, whose parser it shares.
+type Scanner struct {
+	d     *Decoder
+	stack []scanFrame
+	err   error
+}
+
+// NewScanner returns a scanner that reads from r. Like NewDecoder, it uses
+// r directly when r supports byte-wise reads.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{d: NewDecoder(r)}
+}
+
+// SetLimits replaces the limits applied to each top-level value scanned
+// from now on.
+func (s *Scanner) SetLimits(limits Limits) {
+	s.d.SetLimits(limits)
+}
+
+// DisallowNonCanonical makes the Scanner fail on input that is not
+// canonical bencode instead of recording a warning.
+func (s *Scanner) DisallowNonCanonical() {
+	s.d.DisallowNonCanonical()
+}
+
+// Warnings returns the non-canonical input found in the current top-level
+// value, or the last one once the input is exhausted.
+func (s *Scanner) Warnings() []*CanonicalError {
+	return s.d.Warnings()
+}
+
+// InputOffset returns the number of bytes consumed by the tokens read so far.
+func (s *Scanner) InputOffset() int64 {
+	return s.d.InputOffset()
+}
+
+// Depth returns the number of lists and dicts the Scanner is inside of.
+func (s *Scanner) Depth() int {
+	return len(s.stack)
+}
+
+// Next returns the next token. Once a top-level value is complete the
+// next call starts on the following one, and io.EOF is returned when the
+// input ends before a new value starts. Errors are sticky.
+func (s *Scanner) Next() (Token, error) {
+	if s.err != nil {
+		return Token{}, s.err
+	}
+	token, err := s.scan(false)
+	if err != nil {
+		s.err = err
+	}
+	return token, err
+}
+
+// Skip reads past the next token like Next, along with the rest of the
+// value it begins: the whole of a list or dict, or the value of a key.
+func (s *Scanner) Skip() error {
+	if s.err != nil {
+		return s.err
+	}
+	_, err := s.scan(true)
+	if err != nil {
+		s.err = err
+	}
+	return err
+}
+
+func (s *Scanner) top() *scanFrame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return &s.stack[len(s.stack)-1]
+}
+
+// read reads the first byte of the next token.
+func (s *Scanner) read() (byte, error) {
+	d := s.d
+	top := s.top()
+	if top == nil {
+		d.valueStart = d.offset
+		d.depth = 0
+		d.items = 0
+		d.keyBytes = d.keyBytes[:0]
+		d.keyEnds = d.keyEnds[:0]
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		d.offset++
+		d.warnings = nil
+		return b, nil
+	}
+
+	switch {
+	case !top.dict:
+		return d.next("list element or 'e'")
+	case top.afterKey:
+		return d.next("dictionary value")
+	}
+	return d.next("dictionary key or 'e'")
+}
+
+func (s *Scanner) scan(skip bool) (Token, error) {
+	d := s.d
+	b, err := s.read()
+	if err != nil {
+		return Token{}, err
+	}
+	offset := d.offset - 1
+
+	if top := s.top(); top != nil {
+		switch {
+		case b == 'e' && !top.afterKey:
+			s.pop()
+			return Token{Kind: End, Offset: offset}, nil
+		case top.dict && !top.afterKey:
+			if err := d.countItem(); err != nil {
+				return Token{}, err
+			}
+			key, err := d.readKeyScratch(b)
+			if err != nil {
+				return Token{}, err
+			}
+			if err := d.addKey(top.keys, offset, key); err != nil {
+				return Token{}, err
+			}
+			if !skip {
+				top.afterKey = true
+				return Token{Kind: Key, Offset: offset, Value: key}, nil
+			}
+			if b, err = d.next("dictionary value"); err != nil {
+				return Token{}, err
+			}
+			offset = d.offset - 1
+		case top.dict:
+			top.afterKey = false
+		default:
+			if err := d.countItem(); err != nil {
+				return Token{}, err
+			}
+		}
+	}
+
+	if skip {
+		return Token{}, d.skipValue(b)
+	}
+	return s.value(b, offset)
+}
+
+// value returns the token for the value starting with b.
+func (s *Scanner) value(b byte, offset int64) (Token, error) {
+	d := s.d
+	switch {
+	case b == 'i':
+		digits, err := d.readIntDigits()
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Kind: Integer, Offset: offset, Value: digits}, nil
+	case b == 'l':
+		if err := d.enter(); err != nil {
+			return Token{}, err
+		}
+		s.stack = append(s.stack, scanFrame{})
+		return Token{Kind: ListBegin, Offset: offset}, nil
+	case b == 'd':
+		if err := d.enter(); err != nil {
+			return Token{}, err
+		}
+		s.stack = append(s.stack, scanFrame{dict: true, keys: d.beginKeys()})
+		return Token{Kind: DictBegin, Offset: offset}, nil
+	case isDigit(b):
+		value, err := d.readStringScratch(b)
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Kind: String, Offset: offset, Value: value}, nil
+	}
+	return Token{}, d.syntaxError(offset, "value", "invalid character %q", b)
+}
+
+// pop leaves the list or dict whose End was just read.
+func (s *Scanner) pop() {
+	top := s.top()
+	if top.dict {
+		s.d.endKeys(top.keys)
+	}
+	s.d.leave()
+	s.stack = s.stack[:len(s.stack)-1]
+}
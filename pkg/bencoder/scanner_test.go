@@ -0,0 +1,195 @@
+package bencoder
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// scanned is a Token with its Value copied out, since the scanner reuses it.
+type scanned struct {
+	Kind   TokenKind
+	Offset int64
+	Value  string
+}
+
+func scanAll(s *Scanner) ([]scanned, error) {
+	var tokens []scanned
+	for {
+		token, err := s.Next()
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, scanned{token.Kind, token.Offset, string(token.Value)})
+	}
+}
+
+func TestScanner_Next(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []scanned
+		wantErr error
+	}{
+		{
+			name: "Dict With List",
+			data: "d3:barli1ei-2ee3:foo4:spame",
+			want: []scanned{
+				{DictBegin, 0, ""},
+				{Key, 1, "bar"},
+				{ListBegin, 6, ""},
+				{Integer, 7, "1"},
+				{Integer, 10, "-2"},
+				{End, 14, ""},
+				{Key, 15, "foo"},
+				{String, 20, "spam"},
+				{End, 26, ""},
+			},
+		},
+		{
+			name: "Back To Back Values",
+			data: "i1ede0:",
+			want: []scanned{
+				{Integer, 0, "1"},
+				{DictBegin, 3, ""},
+				{End, 4, ""},
+				{String, 5, ""},
+			},
+		},
+		{
+			name:    "Truncated List",
+			data:    "li1e",
+			want:    []scanned{{ListBegin, 0, ""}, {Integer, 1, "1"}},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "Missing Dict Value",
+			data:    "d3:fooe",
+			want:    []scanned{{DictBegin, 0, ""}, {Key, 1, "foo"}},
+			wantErr: &SyntaxError{Offset: 6, Expected: "value", msg: "invalid character 'e'"},
+		},
+		{
+			name:    "Duplicate Key",
+			data:    "d1:ai1e1:ai2ee",
+			want:    []scanned{{DictBegin, 0, ""}, {Key, 1, "a"}, {Integer, 4, "1"}},
+			wantErr: &SyntaxError{Offset: 7, Expected: "unique key", msg: "duplicate dictionary key \"a\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner(iotest.OneByteReader(strings.NewReader(tt.data)))
+			got, err := scanAll(scanner)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr == nil {
+				tt.wantErr = io.EOF
+			}
+			var syntaxErr *SyntaxError
+			if errors.As(tt.wantErr, &syntaxErr) {
+				if !reflect.DeepEqual(err, tt.wantErr) {
+					t.Errorf("Next() error = %#v, want %#v", err, tt.wantErr)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Next() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, again := scanner.Next(); again != err {
+				t.Errorf("Next() after error = %v, want %v", again, err)
+			}
+		})
+	}
+}
+
+func TestScanner_Skip(t *testing.T) {
+	data := "d4:infod6:lengthi5e4:name1:ae4:listli1eli2eee4:spam4:eggse"
+	scanner := NewScanner(strings.NewReader(data))
+
+	expect := func(kind TokenKind, value string) {
+		t.Helper()
+		token, err := scanner.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if token.Kind != kind || string(token.Value) != value {
+			t.Fatalf("Next() = %v %q, want %v %q", token.Kind, token.Value, kind, value)
+		}
+	}
+
+	expect(DictBegin, "")
+	// skipping at a key skips the key and its value
+	if err := scanner.Skip(); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	expect(Key, "list")
+	expect(ListBegin, "")
+	// skipping a list element skips the whole nested list
+	if err := scanner.Skip(); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	if err := scanner.Skip(); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	expect(End, "")
+	expect(Key, "spam")
+	if scanner.Depth() != 1 {
+		t.Errorf("Depth() = %d, want 1", scanner.Depth())
+	}
+	// skipping after a key skips just its value
+	if err := scanner.Skip(); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	expect(End, "")
+	if scanner.InputOffset() != int64(len(data)) {
+		t.Errorf("InputOffset() = %d, want %d", scanner.InputOffset(), len(data))
+	}
+	if _, err := scanner.Next(); err != io.EOF {
+		t.Errorf("Next() error = %v, want io.EOF", err)
+	}
+}
+
+func TestScanner_Limits(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("lllleeee"))
+	scanner.SetLimits(Limits{MaxDepth: 3})
+	_, err := scanAll(scanner)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Next() error = %v, want ErrLimitExceeded", err)
+	}
+}
+
+func TestScanner_Canonical(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("d1:bi0e1:ai-0ee"))
+	if _, err := scanAll(scanner); err != io.EOF {
+		t.Fatalf("Next() error = %v", err)
+	}
+	var reasons []error
+	for _, warning := range scanner.Warnings() {
+		reasons = append(reasons, warning.Err)
+	}
+	if !reflect.DeepEqual(reasons, []error{ErrUnsortedKeys, ErrNegativeZero}) {
+		t.Errorf("Warnings() = %v", scanner.Warnings())
+	}
+
+	// each top-level value starts a new list of warnings
+	stream := NewScanner(strings.NewReader("i-0ei1e"))
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if len(stream.Warnings()) != 1 {
+		t.Errorf("Warnings() = %v, expected one", stream.Warnings())
+	}
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if len(stream.Warnings()) != 0 {
+		t.Errorf("Warnings() after a canonical value = %v", stream.Warnings())
+	}
+
+	strict := NewScanner(strings.NewReader("d1:bi0e1:ai0ee"))
+	strict.DisallowNonCanonical()
+	if _, err := scanAll(strict); !errors.Is(err, ErrUnsortedKeys) {
+		t.Errorf("Next() error = %v, want ErrUnsortedKeys", err)
+	}
+}
@@ -1,104 +1,556 @@
 package bencoder
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 )
 
-// setField sets the value of a struct field based on its bencode tag.
-func setField(obj interface{}, name string, value interface{}) error {
-	structValue := reflect.ValueOf(obj).Elem()
-	structType := structValue.Type()
-	for i := 0; i < structType.NumField(); i++ {
-		field := structType.Field(i)
-		tag := field.Tag.Get("bencode")
-		if tag != name {
-			continue
+// decodeInto decodes the value starting with b straight into target.
+func (d *Decoder) decodeInto(b byte, target reflect.Value) error {
+	return typeDecoder(target.Type())(d, b, target)
+}
+
+// newTypeDecoder builds the decoder for t. Every target it is used on is
+// addressable, so methods with pointer receivers are always reachable.
+func newTypeDecoder(t reflect.Type) decoderFunc {
+	switch t {
+	case valueType:
+		return valueDecoder
+	case bigIntType:
+		return bigIntDecoder
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return unmarshalerDecoder
+	}
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return newTextUnmarshalerDecoder(newKindDecoder(t))
+	}
+	return newKindDecoder(t)
+}
+
+func newKindDecoder(t reflect.Type) decoderFunc {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return newPtrDecoder(t)
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return interfaceDecoder
+		}
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return intDecoder
+	case reflect.String:
+		return stringDecoder
+	case reflect.Slice:
+		if t == rawMessageType {
+			return rawDecoder
 		}
-		fieldValue := structValue.Field(i)
-		if !fieldValue.CanSet() {
-			return errors.New("cannot set field " + name)
+		return newSliceDecoder(t)
+	case reflect.Array:
+		return newArrayDecoder(t)
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return newMapDecoder(t)
 		}
+	case reflect.Struct:
+		return newStructDecoder(t)
+	}
+	return mismatchDecoder
+}
 
-		val := reflect.ValueOf(value)
-		if err := assignValue(fieldValue, val); err != nil {
-			return err
+func mismatchDecoder(d *Decoder, b byte, target reflect.Value) error {
+	return d.mismatch(b, target)
+}
+
+func newPtrDecoder(t reflect.Type) decoderFunc {
+	elementDecoder := typeDecoder(t.Elem())
+	return func(d *Decoder, b byte, target reflect.Value) error {
+		if target.IsNil() {
+			target.Set(reflect.New(t.Elem()))
 		}
-		return nil
+		return elementDecoder(d, b, target.Elem())
 	}
+}
+
+// interfaceDecoder stores the generic decoded tree in an empty interface.
+func interfaceDecoder(d *Decoder, b byte, target reflect.Value) error {
+	value, err := d.readElement(b)
+	if err != nil {
+		return err
+	}
+	target.Set(reflect.ValueOf(value))
 	return nil
 }
 
-// assignValue handles the assignment of values based on their types.
-func assignValue(target reflect.Value, value reflect.Value) error {
-	if target.Kind() == value.Kind() && target.Kind() != reflect.Slice {
-		target.Set(value)
-		return nil
+func intDecoder(d *Decoder, b byte, target reflect.Value) error {
+	if b != 'i' {
+		return d.mismatch(b, target)
+	}
+	return d.readIntInto(d.offset-1, target)
+}
+
+func stringDecoder(d *Decoder, b byte, target reflect.Value) error {
+	if !isDigit(b) {
+		return d.mismatch(b, target)
+	}
+	value, err := d.readStringScratch(b)
+	if err != nil {
+		return err
+	}
+	target.SetString(string(value))
+	return nil
+}
+
+func rawDecoder(d *Decoder, b byte, target reflect.Value) error {
+	raw, err := d.readRaw(b)
+	if err != nil {
+		return err
+	}
+	target.SetBytes(raw)
+	return nil
+}
+
+// saveError keeps the first error that did not stop decoding.
+func (d *Decoder) saveError(err error) {
+	if d.savedError == nil {
+		d.savedError = err
+	}
+}
+
+// typeError records an UnmarshalTypeError for the value at offset.
+func (d *Decoder) typeError(value string, t reflect.Type, offset int64) {
+	d.saveError(&UnmarshalTypeError{Value: value, Type: t, Offset: offset, Path: formatPath(d.path)})
+}
+
+// mismatch skips the value starting with b, which cannot be stored in target.
+func (d *Decoder) mismatch(b byte, target reflect.Value) error {
+	start := d.offset - 1
+	if err := d.skipValue(b); err != nil {
+		return err
+	}
+	d.typeError(describeToken(b), target.Type(), start)
+	return nil
+}
+
+// describeToken names the kind of value that starts with b.
+func describeToken(b byte) string {
+	switch {
+	case b == 'i':
+		return "integer"
+	case b == 'l':
+		return "list"
+	case b == 'd':
+		return "dictionary"
+	}
+	return "string"
+}
+
+func (d *Decoder) pushKey(key string) {
+	d.path = append(d.path, pathSegment{key: key})
+}
+
+func (d *Decoder) pushIndex(index int) {
+	d.path = append(d.path, pathSegment{index: index, isIndex: true})
+}
+
+func (d *Decoder) pop() {
+	d.path = d.path[:len(d.path)-1]
+}
+
+// readRaw reads the value starting with b and returns its exact encoding.
+func (d *Decoder) readRaw(b byte) (RawMessage, error) {
+	d.raw = append(d.raw[:0], b)
+	d.recording = true
+	err := d.skipValue(b)
+	d.recording = false
+	if err != nil {
+		return nil, err
+	}
+	raw := make(RawMessage, len(d.raw))
+	copy(raw, d.raw)
+	return raw, nil
+}
+
+// readIntInto reads an integer into a bool or integer target, checking it
+// fits the target's width. Bools are encoded as 0 or 1.
+func (d *Decoder) readIntInto(start int64, target reflect.Value) error {
+	digits, err := d.readIntDigits()
+	if err != nil {
+		return err
 	}
 
-	// Handle specific type conversions
 	switch target.Kind() {
-	case reflect.String:
-		if isByteSlice(value) {
-			target.SetString(string(value.Bytes()))
+	case reflect.Bool:
+		if len(digits) == 1 && (digits[0] == '0' || digits[0] == '1') {
+			target.SetBool(digits[0] == '1')
 			return nil
 		}
-	case reflect.Struct:
-		if value.Kind() == reflect.Map {
-			return assignStruct(target, value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value, ok := parseInt(digits, target.Type().Bits()); ok {
+			target.SetInt(value)
+			return nil
 		}
-	case reflect.Slice:
-		if value.Kind() == reflect.Slice {
-			return assignSlice(target, value)
+	default:
+		if value, ok := parseUint(digits, target.Type().Bits()); ok {
+			target.SetUint(value)
+			return nil
 		}
 	}
 
-	return fmt.Errorf("type mismatch for field %v", target.Type().Name())
+	d.typeError("integer "+string(digits), target.Type(), start)
+	return nil
 }
 
-// isByteSlice checks if a reflect.Value is a slice of bytes.
-func isByteSlice(value reflect.Value) bool {
-	return value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8
+// newStructDecoder builds the decoder filling a struct from a dict. Keys
+// are looked up in the cached field table; keys it does not have are
+// skipped without being decoded.
+func newStructDecoder(t reflect.Type) decoderFunc {
+	info := cachedStructInfo(t)
+	if info.extraErr != nil {
+		return func(d *Decoder, b byte, target reflect.Value) error {
+			return info.extraErr
+		}
+	}
+	decoders := make([]decoderFunc, len(info.fields))
+	for i, field := range info.fields {
+		decoders[i] = typeDecoder(field.typ)
+	}
+
+	return func(d *Decoder, b byte, target reflect.Value) error {
+		if b != 'd' {
+			return d.mismatch(b, target)
+		}
+		return d.readStruct(target, info, decoders)
+	}
 }
 
-// assignStruct assigns a map's values to a struct's fields recursively.
-func assignStruct(target reflect.Value, value reflect.Value) error {
-	newStruct := reflect.New(target.Type()).Interface()
-	for k, v := range value.Interface().(map[string]interface{}) {
-		if err := setField(newStruct, k, v); err != nil {
+func (d *Decoder) readStruct(target reflect.Value, info *structInfo, decoders []decoderFunc) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	keys := d.beginKeys()
+	defer d.endKeys(keys)
+	seen := newFieldSet(len(info.fields))
+	for {
+		b, err := d.next("dictionary key or 'e'")
+		if err != nil {
+			return err
+		}
+		if b == 'e' {
+			for i, field := range info.fields {
+				if field.required && !seen.has(i) {
+					d.saveError(fmt.Errorf("missing required key %q for Go type %s%s", field.name, target.Type(), d.atPath()))
+				}
+			}
+			return nil
+		}
+		if err := d.countItem(); err != nil {
+			return err
+		}
+		start := d.offset - 1
+		key, err := d.readKeyScratch(b)
+		if err != nil {
+			return err
+		}
+		if err := d.addKey(keys, start, key); err != nil {
+			return err
+		}
+		i, ok := info.byName[string(key)]
+
+		b, err = d.next("dictionary value")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := d.readExtra(target, info, key, b); err != nil {
+				return err
+			}
+			continue
+		}
+		seen.add(i)
+
+		field := info.fields[i]
+		d.pushKey(field.name)
+		fieldValue, ok := fieldByIndexAlloc(target, field.index)
+		if ok {
+			err = decoders[i](d, b, fieldValue)
+		} else {
+			err = d.mismatch(b, fieldValue)
+		}
+		d.pop()
+		if err != nil {
 			return err
 		}
 	}
-	target.Set(reflect.ValueOf(newStruct).Elem())
+}
+
+// readExtra stores a key no field decodes in the struct's extra field, or
+// reads past its value when there is none.
+func (d *Decoder) readExtra(target reflect.Value, info *structInfo, key []byte, b byte) error {
+	if info.extra == nil {
+		return d.skipValue(b)
+	}
+	name := string(key) // key is overwritten by readRaw
+	raw, err := d.readRaw(b)
+	if err != nil {
+		return err
+	}
+	extra, ok := fieldByIndexAlloc(target, info.extra.index)
+	if !ok {
+		return nil
+	}
+	if extra.IsNil() {
+		extra.Set(reflect.MakeMap(extra.Type()))
+	}
+	extra.SetMapIndex(reflect.ValueOf(name), reflect.ValueOf(raw))
 	return nil
 }
 
-// assignSlice handles complex slice assignments.
-func assignSlice(target reflect.Value, value reflect.Value) error {
-	newSlice := reflect.MakeSlice(target.Type(), value.Len(), value.Len())
+// fieldSet records which fields of a struct were present in a dict. Most
+// structs fit in the bitmask, so tracking them costs no allocation.
+type fieldSet struct {
+	mask  uint64
+	large []bool
+}
 
-	for i := 0; i < value.Len(); i++ {
-		elem := reflect.ValueOf(value.Index(i).Interface())
-		targetElem := newSlice.Index(i)
+func newFieldSet(n int) fieldSet {
+	if n > 64 {
+		return fieldSet{large: make([]bool, n)}
+	}
+	return fieldSet{}
+}
 
-		if targetElem.Kind() == reflect.Slice && elem.Kind() == reflect.Slice {
-			// Recursively handle slices of slices
-			if err := assignSlice(targetElem, elem); err != nil {
+func (s *fieldSet) add(i int) {
+	if s.large != nil {
+		s.large[i] = true
+		return
+	}
+	s.mask |= 1 << uint(i)
+}
+
+func (s *fieldSet) has(i int) bool {
+	if s.large != nil {
+		return s.large[i]
+	}
+	return s.mask&(1<<uint(i)) != 0
+}
+
+// atPath formats the current key path as a suffix for error messages.
+func (d *Decoder) atPath() string {
+	if len(d.path) == 0 {
+		return ""
+	}
+	return " at " + formatPath(d.path)
+}
+
+// newSliceDecoder builds the decoder for a slice, which is read from a
+// list or, for byte slices, a byte string.
+func newSliceDecoder(t reflect.Type) decoderFunc {
+	elementDecoder := typeDecoder(t.Elem())
+	isBytes := t.Elem().Kind() == reflect.Uint8
+	return func(d *Decoder, b byte, target reflect.Value) error {
+		if b == 'l' {
+			return d.readSlice(target, elementDecoder)
+		}
+		if isBytes && isDigit(b) {
+			value, err := d.readString(b)
+			if err != nil {
 				return err
 			}
-		} else if targetElem.Kind() == reflect.Struct && elem.Kind() == reflect.Map {
-			// Convert map to struct (for cases like []File)
-			if err := assignStruct(targetElem, elem); err != nil {
+			target.SetBytes(value)
+			return nil
+		}
+		return d.mismatch(b, target)
+	}
+}
+
+// readSlice decodes each list element in place, growing target's backing
+// array as needed rather than decoding into a temporary and copying it.
+func (d *Decoder) readSlice(target reflect.Value, elementDecoder decoderFunc) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	sliceType := target.Type()
+	zero := reflect.Zero(sliceType.Elem())
+	if target.IsNil() {
+		target.Set(reflect.MakeSlice(sliceType, 0, 0))
+	}
+	target.SetLen(0)
+	for i := 0; ; i++ {
+		b, err := d.next("list element or 'e'")
+		if err != nil {
+			return err
+		}
+		if b == 'e' {
+			return nil
+		}
+		if err := d.countItem(); err != nil {
+			return err
+		}
+		if i == target.Cap() {
+			grown := reflect.MakeSlice(sliceType, i, i+i/2+4)
+			reflect.Copy(grown, target)
+			target.Set(grown)
+		}
+		target.SetLen(i + 1)
+		element := target.Index(i)
+		element.Set(zero)
+		d.pushIndex(i)
+		err = elementDecoder(d, b, element)
+		d.pop()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// newArrayDecoder builds the decoder for an array, which is read from a
+// list or, for byte arrays, a byte string of exactly the array's length.
+func newArrayDecoder(t reflect.Type) decoderFunc {
+	elementDecoder := typeDecoder(t.Elem())
+	isBytes := t.Elem().Kind() == reflect.Uint8
+	return func(d *Decoder, b byte, target reflect.Value) error {
+		if b == 'l' {
+			return d.readArray(target, elementDecoder)
+		}
+		if isBytes && isDigit(b) {
+			start := d.offset - 1
+			value, err := d.readStringScratch(b)
+			if err != nil {
 				return err
 			}
-		} else {
-			if err := assignValue(targetElem, elem); err != nil {
-				return err
+			if len(value) != target.Len() {
+				d.typeError(fmt.Sprintf("%d byte string", len(value)), target.Type(), start)
+				return nil
 			}
+			reflect.Copy(target, reflect.ValueOf(value))
+			return nil
 		}
+		return d.mismatch(b, target)
 	}
+}
 
-	target.Set(newSlice)
+// readArray fills a fixed size array from a list. Missing elements are
+// zeroed and surplus ones are skipped and reported.
+func (d *Decoder) readArray(target reflect.Value, elementDecoder decoderFunc) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	i := 0
+	for ; ; i++ {
+		b, err := d.next("list element or 'e'")
+		if err != nil {
+			return err
+		}
+		if b == 'e' {
+			break
+		}
+		if err := d.countItem(); err != nil {
+			return err
+		}
+		d.pushIndex(i)
+		if i >= target.Len() {
+			err = d.mismatch(b, target)
+		} else {
+			err = elementDecoder(d, b, target.Index(i))
+		}
+		d.pop()
+		if err != nil {
+			return err
+		}
+	}
+	for ; i < target.Len(); i++ {
+		target.Index(i).Set(reflect.Zero(target.Type().Elem()))
+	}
 	return nil
 }
+
+func newMapDecoder(t reflect.Type) decoderFunc {
+	elementDecoder := typeDecoder(t.Elem())
+	return func(d *Decoder, b byte, target reflect.Value) error {
+		if b != 'd' {
+			return d.mismatch(b, target)
+		}
+		return d.readMap(target, elementDecoder)
+	}
+}
+
+func (d *Decoder) readMap(target reflect.Value, elementDecoder decoderFunc) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	mapType := target.Type()
+	if target.IsNil() {
+		target.Set(reflect.MakeMap(mapType))
+	}
+
+	// one element and key are reused for every entry, SetMapIndex copies them
+	element := reflect.New(mapType.Elem()).Elem()
+	zero := reflect.Zero(mapType.Elem())
+	mapKey := reflect.New(mapType.Key()).Elem()
+	keys := d.beginKeys()
+	defer d.endKeys(keys)
+	for {
+		b, err := d.next("dictionary key or 'e'")
+		if err != nil {
+			return err
+		}
+		if b == 'e' {
+			return nil
+		}
+		if err := d.countItem(); err != nil {
+			return err
+		}
+		start := d.offset - 1
+		key, err := d.readKeyScratch(b)
+		if err != nil {
+			return err
+		}
+		if err := d.addKey(keys, start, key); err != nil {
+			return err
+		}
+		name := string(key)
+
+		b, err = d.next("dictionary value")
+		if err != nil {
+			return err
+		}
+		element.Set(zero)
+		d.pushKey(name)
+		err = elementDecoder(d, b, element)
+		d.pop()
+		if err != nil {
+			return err
+		}
+		mapKey.SetString(name)
+		target.SetMapIndex(mapKey, element)
+	}
+}
+
+// fieldByIndexAlloc is reflect.Value.FieldByIndex, allocating nil embedded
+// struct pointers on the way to the field. It reports false, along with the
+// pointer it could not set, when the pointer is to an unexported struct.
+func fieldByIndexAlloc(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				if !value.CanSet() {
+					return value, false
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
@@ -0,0 +1,101 @@
+package bencoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Common struct {
+	Name    string `bencode:"name"`
+	Comment string `bencode:"comment"`
+}
+
+type Extra struct {
+	Source string `bencode:"source"`
+}
+
+type allTypes struct {
+	Common
+	*Extra
+	Int       int               `bencode:"int"`
+	Int8      int8              `bencode:"int8"`
+	Uint32    uint32            `bencode:"uint32"`
+	Uint64    uint64            `bencode:"uint64"`
+	Private   bool              `bencode:"private"`
+	Length    *int64            `bencode:"length"`
+	Missing   *int64            `bencode:"missing"`
+	Hash      [4]byte           `bencode:"hash"`
+	Pair      [2]string         `bencode:"pair"`
+	Counts    map[string]int    `bencode:"counts"`
+	Nested    map[string][]bool `bencode:"nested"`
+	Anything  interface{}       `bencode:"anything"`
+	Comment   string            `bencode:"comment"` // shadows Common.Comment
+	Forgotten interface{}       `bencode:"forgotten"`
+}
+
+func TestUnmarshal_AllTypes(t *testing.T) {
+	length := int64(42)
+	want := allTypes{
+		Common:   Common{Name: "name"},
+		Extra:    &Extra{Source: "src"},
+		Int:      -1,
+		Int8:     127,
+		Uint32:   4294967295,
+		Uint64:   18446744073709551615,
+		Private:  true,
+		Length:   &length,
+		Hash:     [4]byte{'a', 'b', 'c', 'd'},
+		Pair:     [2]string{"x", "y"},
+		Counts:   map[string]int{"a": 1, "b": 2},
+		Nested:   map[string][]bool{"flags": {true, false}},
+		Anything: []interface{}{int64(1), []byte("two"), map[string]interface{}{"three": int64(3)}},
+		Comment:  "outer",
+	}
+
+	data, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	expected := "d8:anythingli1e3:twod5:threei3eee7:comment5:outer6:countsd1:ai1e1:bi2ee" +
+		"4:hash4:abcd3:inti-1e4:int8i127e6:lengthi42e4:name4:name6:nestedd5:flagsli1ei0eee" +
+		"4:pairl1:x1:ye7:privatei1e6:source3:src6:uint32i4294967295e6:uint64i18446744073709551615ee"
+	if string(data) != expected {
+		t.Errorf("Marshal() got = %q, want %q", data, expected)
+	}
+
+	var got allTypes
+	if err := NewSimpleBencoder().Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() got = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshal_TypeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "Overflow", data: "d4:int8i128e4:name1:xe", wantErr: "cannot decode integer 128 into Go value of type int8"},
+		{name: "Negative Unsigned", data: "d6:uint32i-1e4:name1:xe", wantErr: "cannot decode integer -1 into Go value of type uint32"},
+		{name: "Bool Out Of Range", data: "d4:name1:x7:privatei2ee", wantErr: "cannot decode integer 2 into Go value of type bool"},
+		{name: "Wrong Kind", data: "d3:int3:abc4:name1:xe", wantErr: "cannot decode string into Go value of type int"},
+		{name: "Wrong Hash Length", data: "d4:hash3:abc4:name1:xe", wantErr: "cannot decode 3 byte string into Go value of type [4]uint8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got allTypes
+			err := NewSimpleBencoder().Unmarshal([]byte(tt.data), &got)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Unmarshal() error = %v, want %q", err, tt.wantErr)
+			}
+			// the rest of the document is still decoded
+			if got.Name != "x" {
+				t.Errorf("Unmarshal() Name got = %q, want %q", got.Name, "x")
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package bencoder
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+// ValueKind is the kind of a Value.
+type ValueKind int
+
+const (
+	IntKind ValueKind = iota + 1
+	BigIntKind
+	BytesKind
+	ListKind
+	DictKind
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case IntKind:
+		return "Int"
+	case BigIntKind:
+		return "BigInt"
+	case BytesKind:
+		return "Bytes"
+	case ListKind:
+		return "List"
+	case DictKind:
+		return "Dict"
+	}
+	return "invalid"
+}
+
+// Value is any bencoded value, kept in a form that encodes back to exactly
+// the bytes it was decoded from: integers of any size, byte strings that
+// need not be text, and dicts whose keys keep their order and may be
+// binary. The zero Value is invalid and cannot be encoded.
+type Value struct {
+	kind  ValueKind
+	int   int64
+	big   *big.Int
+	bytes []byte
+	list  []Value
+	dict  []DictEntry
+}
+
+// DictEntry is a key and its value in a dict Value.
+type DictEntry struct {
+	Key   []byte
+	Value Value
+}
+
+var (
+	valueType  = reflect.TypeOf(Value{})
+	bigIntType = reflect.TypeOf(big.Int{})
+)
+
+// NewInt returns an integer Value.
+func NewInt(value int64) Value {
+	return Value{kind: IntKind, int: value}
+}
+
+// NewBigInt returns an integer Value of any size. It keeps its own copy of value.
+func NewBigInt(value *big.Int) Value {
+	return Value{kind: BigIntKind, big: new(big.Int).Set(value)}
+}
+
+// NewBytes returns a byte string Value.
+func NewBytes(value []byte) Value {
+	return Value{kind: BytesKind, bytes: value}
+}
+
+// NewString returns a byte string Value holding the bytes of value.
+func NewString(value string) Value {
+	return Value{kind: BytesKind, bytes: []byte(value)}
+}
+
+// NewList returns a list Value.
+func NewList(elements ...Value) Value {
+	if elements == nil {
+		elements = []Value{}
+	}
+	return Value{kind: ListKind, list: elements}
+}
+
+// NewDict returns a dict Value with its entries sorted by key, the order
+// BEP 3 requires. When a key is given more than once the last value is
+// kept, as keys must be unique.
+func NewDict(entries ...DictEntry) Value {
+	sorted := make([]DictEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Key, sorted[j].Key) < 0
+	})
+	unique := sorted[:0]
+	for _, entry := range sorted {
+		if n := len(unique); n > 0 && bytes.Equal(unique[n-1].Key, entry.Key) {
+			unique[n-1] = entry
+			continue
+		}
+		unique = append(unique, entry)
+	}
+	return Value{kind: DictKind, dict: unique}
+}
+
+// Kind returns the kind of v, or 0 for the zero Value.
+func (v Value) Kind() ValueKind {
+	return v.kind
+}
+
+// Int returns the value of an integer, reporting false when v is not an
+// integer or does not fit in an int64.
+func (v Value) Int() (int64, bool) {
+	switch v.kind {
+	case IntKind:
+		return v.int, true
+	case BigIntKind:
+		if v.big.IsInt64() {
+			return v.big.Int64(), true
+		}
+	}
+	return 0, false
+}
+
+// BigInt returns a copy of the value of an integer of either kind.
+func (v Value) BigInt() (*big.Int, bool) {
+	switch v.kind {
+	case IntKind:
+		return big.NewInt(v.int), true
+	case BigIntKind:
+		return new(big.Int).Set(v.big), true
+	}
+	return nil, false
+}
+
+// Bytes returns the contents of a byte string.
+func (v Value) Bytes() ([]byte, bool) {
+	return v.bytes, v.kind == BytesKind
+}
+
+// List returns the elements of a list.
+func (v Value) List() ([]Value, bool) {
+	return v.list, v.kind == ListKind
+}
+
+// Dict returns the entries of a dict in order.
+func (v Value) Dict() ([]DictEntry, bool) {
+	return v.dict, v.kind == DictKind
+}
+
+// Get returns the value of key in a dict. The key may hold binary data.
+func (v Value) Get(key string) (Value, bool) {
+	for _, entry := range v.dict {
+		if string(entry.Key) == key {
+			return entry.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// Len returns the number of bytes, elements or entries of a byte string,
+// list or dict, and 0 for anything else.
+func (v Value) Len() int {
+	switch v.kind {
+	case BytesKind:
+		return len(v.bytes)
+	case ListKind:
+		return len(v.list)
+	case DictKind:
+		return len(v.dict)
+	}
+	return 0
+}
+
+// Equal reports whether v and other encode to the same bytes.
+func (v Value) Equal(other Value) bool {
+	if x, ok := v.BigInt(); ok {
+		y, ok := other.BigInt()
+		return ok && x.Cmp(y) == 0
+	}
+	if v.kind != other.kind {
+		return false
+	}
+	switch v.kind {
+	case BytesKind:
+		return bytes.Equal(v.bytes, other.bytes)
+	case ListKind:
+		if len(v.list) != len(other.list) {
+			return false
+		}
+		for i := range v.list {
+			if !v.list[i].Equal(other.list[i]) {
+				return false
+			}
+		}
+		return true
+	case DictKind:
+		if len(v.dict) != len(other.dict) {
+			return false
+		}
+		for i := range v.dict {
+			if !bytes.Equal(v.dict[i].Key, other.dict[i].Key) || !v.dict[i].Value.Equal(other.dict[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// ParseValue decodes data, which must hold exactly one bencoded value.
+func ParseValue(data []byte) (Value, error) {
+	if len(data) == 0 {
+		return Value{}, errors.New("empty data")
+	}
+	decoder := NewDecoder(bytes.NewReader(data))
+	var value Value
+	if err := decoder.Decode(&value); err != nil {
+		return Value{}, err
+	}
+	if decoder.InputOffset() != int64(len(data)) {
+		return Value{}, &SyntaxError{Offset: decoder.InputOffset(), Expected: "end of input", msg: "trailing data after top-level value"}
+	}
+	return value, nil
+}
+
+func valueDecoder(d *Decoder, b byte, target reflect.Value) error {
+	value, err := d.readValue(b)
+	if err != nil {
+		return err
+	}
+	target.Set(reflect.ValueOf(value))
+	return nil
+}
+
+// readValue reads the value starting with b, keeping dict keys in the
+// order they were read.
+func (d *Decoder) readValue(b byte) (Value, error) {
+	switch {
+	case b == 'i':
+		digits, err := d.readIntDigits()
+		if err != nil {
+			return Value{}, err
+		}
+		if value, ok := parseInt(digits, 64); ok {
+			return NewInt(value), nil
+		}
+		value, _ := new(big.Int).SetString(string(digits), 10)
+		return Value{kind: BigIntKind, big: value}, nil
+	case isDigit(b):
+		value, err := d.readString(b)
+		if err != nil {
+			return Value{}, err
+		}
+		return NewBytes(value), nil
+	case b == 'l':
+		return d.readValueList()
+	case b == 'd':
+		return d.readValueDict()
+	}
+	return Value{}, d.syntaxError(d.offset-1, "value", "invalid character %q", b)
+}
+
+func (d *Decoder) readValueList() (Value, error) {
+	if err := d.enter(); err != nil {
+		return Value{}, err
+	}
+	defer d.leave()
+
+	elements := []Value{}
+	for {
+		b, err := d.next("list element or 'e'")
+		if err != nil {
+			return Value{}, err
+		}
+		if b == 'e' {
+			return Value{kind: ListKind, list: elements}, nil
+		}
+		if err := d.countItem(); err != nil {
+			return Value{}, err
+		}
+		element, err := d.readValue(b)
+		if err != nil {
+			return Value{}, err
+		}
+		elements = append(elements, element)
+	}
+}
+
+func (d *Decoder) readValueDict() (Value, error) {
+	if err := d.enter(); err != nil {
+		return Value{}, err
+	}
+	defer d.leave()
+
+	var entries []DictEntry
+	keys := d.beginKeys()
+	defer d.endKeys(keys)
+	for {
+		b, err := d.next("dictionary key or 'e'")
+		if err != nil {
+			return Value{}, err
+		}
+		if b == 'e' {
+			return Value{kind: DictKind, dict: entries}, nil
+		}
+		if err := d.countItem(); err != nil {
+			return Value{}, err
+		}
+		start := d.offset - 1
+		key, err := d.readKey(b)
+		if err != nil {
+			return Value{}, err
+		}
+		if err := d.addKey(keys, start, key); err != nil {
+			return Value{}, err
+		}
+		b, err = d.next("dictionary value")
+		if err != nil {
+			return Value{}, err
+		}
+		value, err := d.readValue(b)
+		if err != nil {
+			return Value{}, err
+		}
+		entries = append(entries, DictEntry{Key: key, Value: value})
+	}
+}
+
+func valueEncoder(w tokenWriter, value reflect.Value) error {
+	return writeBencodeValue(w, value.Interface().(Value))
+}
+
+// writeBencodeValue writes v with its dict entries in their stored order.
+func writeBencodeValue(w tokenWriter, v Value) error {
+	switch v.kind {
+	case IntKind:
+		writeInt(w, v.int)
+	case BigIntKind:
+		writeBigInt(w, v.big)
+	case BytesKind:
+		writeBytes(w, v.bytes)
+	case ListKind:
+		_ = w.WriteByte('l')
+		for _, element := range v.list {
+			if err := writeBencodeValue(w, element); err != nil {
+				return err
+			}
+		}
+		_ = w.WriteByte('e')
+	case DictKind:
+		_ = w.WriteByte('d')
+		for _, entry := range v.dict {
+			writeBytes(w, entry.Key)
+			if err := writeBencodeValue(w, entry.Value); err != nil {
+				return err
+			}
+		}
+		_ = w.WriteByte('e')
+	default:
+		return errors.New("no data to encode")
+	}
+	return nil
+}
+
+func writeBigInt(w tokenWriter, value *big.Int) {
+	var scratch [64]byte
+	_ = w.WriteByte('i')
+	_, _ = w.Write(value.Append(scratch[:0], 10))
+	_ = w.WriteByte('e')
+}
+
+// bigIntEncoder writes a big.Int as an integer rather than through its
+// MarshalText method.
+func bigIntEncoder(w tokenWriter, value reflect.Value) error {
+	if value.CanAddr() {
+		writeBigInt(w, value.Addr().Interface().(*big.Int))
+		return nil
+	}
+	copied := value.Interface().(big.Int)
+	writeBigInt(w, &copied)
+	return nil
+}
+
+func bigIntDecoder(d *Decoder, b byte, target reflect.Value) error {
+	if b != 'i' {
+		return d.mismatch(b, target)
+	}
+	digits, err := d.readIntDigits()
+	if err != nil {
+		return err
+	}
+	target.Addr().Interface().(*big.Int).SetString(string(digits), 10)
+	return nil
+}
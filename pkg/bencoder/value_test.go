@@ -0,0 +1,163 @@
+package bencoder
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestParseValue_RoundTrip(t *testing.T) {
+	documents := []string{
+		"i0e",
+		"i-9223372036854775808e",
+		"i-123456789012345678901234567890e",
+		"0:",
+		"4:\x00\x01\xfe\xff",
+		"le",
+		"de",
+		"d12:piece layersd32:\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab\xab4:\x00\x01\x02\x03ee",
+		"d1:bi1e1:ali99999999999999999999eee", // unsorted keys keep their order
+	}
+	for _, document := range documents {
+		value, err := ParseValue([]byte(document))
+		if err != nil {
+			t.Fatalf("ParseValue(%q) error = %v", document, err)
+		}
+		encoded, err := Marshal(value)
+		if err != nil {
+			t.Fatalf("Marshal(%q) error = %v", document, err)
+		}
+		if string(encoded) != document {
+			t.Errorf("Marshal(ParseValue(%q)) = %q", document, encoded)
+		}
+	}
+}
+
+func TestParseValue_Errors(t *testing.T) {
+	for _, document := range []string{"", "i1ei2e", "d1:ai1e1:ai2ee", "l", "x"} {
+		if _, err := ParseValue([]byte(document)); err == nil {
+			t.Errorf("ParseValue(%q) succeeded", document)
+		}
+	}
+}
+
+func TestValue_Accessors(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	value, err := ParseValue([]byte("d3:bigi123456789012345678901234567890e5:bytes2:\xff\x004:listli7ee5:smalli42ee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Kind() != DictKind || value.Len() != 4 {
+		t.Fatalf("Kind() = %v, Len() = %d", value.Kind(), value.Len())
+	}
+
+	small, _ := value.Get("small")
+	if got, ok := small.Int(); !ok || got != 42 || small.Kind() != IntKind {
+		t.Errorf("small = %d, %v, kind %v", got, ok, small.Kind())
+	}
+	bigValue, _ := value.Get("big")
+	if _, ok := bigValue.Int(); ok || bigValue.Kind() != BigIntKind {
+		t.Errorf("big fits in an int64, kind %v", bigValue.Kind())
+	}
+	if got, ok := bigValue.BigInt(); !ok || got.Cmp(huge) != 0 {
+		t.Errorf("big = %v, %v", got, ok)
+	}
+	raw, _ := value.Get("bytes")
+	if got, ok := raw.Bytes(); !ok || !bytes.Equal(got, []byte{0xff, 0}) {
+		t.Errorf("bytes = %v, %v", got, ok)
+	}
+	list, _ := value.Get("list")
+	if elements, ok := list.List(); !ok || len(elements) != 1 || !elements[0].Equal(NewInt(7)) {
+		t.Errorf("list = %v, %v", elements, ok)
+	}
+	if _, ok := value.Get("missing"); ok {
+		t.Error("Get(missing) found a value")
+	}
+	if _, ok := small.Bytes(); ok {
+		t.Error("Bytes() of an integer succeeded")
+	}
+}
+
+func TestNewDict_SortsKeys(t *testing.T) {
+	value := NewDict(
+		DictEntry{Key: []byte("name"), Value: NewString("demo")},
+		DictEntry{Key: []byte{0x00}, Value: NewList(NewInt(1), NewBigInt(big.NewInt(-2)))},
+		DictEntry{Key: []byte("length"), Value: NewInt(5)},
+	)
+	encoded, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "d1:\x00li1ei-2ee6:lengthi5e4:name4:demoe"; string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+	if _, err := Marshal(Value{}); err == nil {
+		t.Error("Marshal(Value{}) succeeded")
+	}
+}
+
+func TestNewDict_DuplicateKeys(t *testing.T) {
+	value := NewDict(
+		DictEntry{Key: []byte("b"), Value: NewInt(1)},
+		DictEntry{Key: []byte("a"), Value: NewInt(2)},
+		DictEntry{Key: []byte("b"), Value: NewInt(3)},
+		DictEntry{Key: []byte("b"), Value: NewInt(4)},
+	)
+	encoded, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "d1:ai2e1:bi4ee"; string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+	var decoded Value
+	if err := DecodeStrict(encoded, &decoded); err != nil {
+		t.Errorf("DecodeStrict() error = %v", err)
+	}
+}
+
+func TestValue_StructFields(t *testing.T) {
+	type document struct {
+		Count  *big.Int `bencode:"count"`
+		Total  big.Int  `bencode:"total"`
+		Layers Value    `bencode:"layers"`
+	}
+	data := []byte("d5:counti99999999999999999999999e6:layersd2:\x01\x02lee5:totali-1ee")
+
+	var got document
+	if err := NewSimpleBencoder().Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Count.String() != "99999999999999999999999" || got.Total.Int64() != -1 {
+		t.Errorf("Count = %v, Total = %v", got.Count, &got.Total)
+	}
+	if _, ok := got.Layers.Get("\x01\x02"); !ok {
+		t.Errorf("Layers = %v", got.Layers)
+	}
+
+	encoded, err := Marshal(&got)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("Marshal() = %q, want %q", encoded, data)
+	}
+}
+
+func TestDecode_BigInteger(t *testing.T) {
+	got, err := NewSimpleBencoder().Decode([]byte("li1ei18446744073709551616ee"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want, _ := new(big.Int).SetString("18446744073709551616", 10)
+	list := got.([]interface{})
+	if !reflect.DeepEqual(list[0], int64(1)) || list[1].(*big.Int).Cmp(want) != 0 {
+		t.Errorf("Decode() = %v", got)
+	}
+
+	encoded, err := NewSimpleBencoder().Encode(got)
+	if err != nil || string(encoded) != "li1ei18446744073709551616ee" {
+		t.Errorf("Encode() = %q, %v", encoded, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"torrent/pkg/peer"
 	"torrent/pkg/torrent"
+	"torrent/pkg/tracker"
 )
 
 const PieceHashLength = 20 // SHA-1 hash length in bytes
@@ -25,6 +26,7 @@ func (s TorrentStatus) String() string {
 
 type TorrentTask struct {
 	Torrent      *torrent.TorrentFile
+	Trackers     *tracker.Tiers // the trackers to announce to, in BEP 12 tiers
 	Peers        []peer.Peer
 	PieceStatus  []bool // true if the piece is downloaded
 	Availability []int  // number of peers that have each piece
@@ -37,13 +39,13 @@ type TorrentTask struct {
 }
 
 func NewTorrentTask(torrent *torrent.TorrentFile) (*TorrentTask, error) {
-	if len(torrent.Info.Pieces)%PieceHashLength != 0 {
-		return nil, fmt.Errorf("invalid pieces length: not a multiple of %d", PieceHashLength)
+	numPieces, err := countPieces(torrent)
+	if err != nil {
+		return nil, err
 	}
-
-	numPieces := len(torrent.Info.Pieces) / PieceHashLength
 	return &TorrentTask{
 		Torrent:      torrent,
+		Trackers:     tracker.NewTiers(torrent, nil),
 		Peers:        []peer.Peer{},
 		PieceStatus:  make([]bool, numPieces),
 		Availability: make([]int, numPieces),
@@ -51,12 +53,67 @@ func NewTorrentTask(torrent *torrent.TorrentFile) (*TorrentTask, error) {
 	}, nil
 }
 
+// countPieces returns the number of pieces of a torrent of any version. A
+// hybrid torrent aligns its v1 pieces with the v2 ones, so either count
+// serves; both kinds of hashes are checked.
+func countPieces(t *torrent.TorrentFile) (int, error) {
+	version := t.Info.Version()
+	if version != torrent.V1 {
+		if err := t.CheckPieceLayers(); err != nil {
+			return 0, err
+		}
+	}
+	if version == torrent.V2 {
+		return t.Info.NumPiecesV2(), nil
+	}
+
+	if len(t.Info.Pieces)%PieceHashLength != 0 {
+		return 0, fmt.Errorf("invalid pieces length: not a multiple of %d", PieceHashLength)
+	}
+	return len(t.Info.Pieces) / PieceHashLength, nil
+}
+
 func (tt *TorrentTask) AddPeer(peer peer.Peer) {
 	tt.mu.Lock()
 	defer tt.mu.Unlock()
 	tt.Peers = append(tt.Peers, peer)
 }
 
+// PeerSource is where a peer was learned of.
+type PeerSource int
+
+const (
+	SourceTracker PeerSource = iota
+	SourceDHT
+	SourcePEX
+	SourceLSD // local service discovery
+	SourceIncoming
+)
+
+func (s PeerSource) String() string {
+	return [...]string{"tracker", "DHT", "PEX", "LSD", "incoming"}[s]
+}
+
+// AllowsPeerSource reports whether peers from source may join the task. A
+// private torrent only takes peers from its trackers and those connecting
+// to us, never from DHT, PEX or local discovery.
+func (tt *TorrentTask) AllowsPeerSource(source PeerSource) bool {
+	if !tt.Torrent.Info.IsPrivate() {
+		return true
+	}
+	return source == SourceTracker || source == SourceIncoming
+}
+
+// AddPeerFrom adds a peer learned of from source, or fails if the torrent
+// does not allow that source.
+func (tt *TorrentTask) AddPeerFrom(peer peer.Peer, source PeerSource) error {
+	if !tt.AllowsPeerSource(source) {
+		return fmt.Errorf("private torrent does not accept peers from %s", source)
+	}
+	tt.AddPeer(peer)
+	return nil
+}
+
 func (tt *TorrentTask) UpdatePieceStatus(index int) {
 	tt.mu.Lock()
 	defer tt.mu.Unlock()
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"torrent/pkg/peer"
@@ -48,6 +49,57 @@ func TestNewTorrentTask_InvalidPiecesLength(t *testing.T) {
 	assert.Nil(t, tt)
 }
 
+// newV2Info returns a v2 info dict for a single file of length bytes of
+// zeros, along with its piece layers.
+func newV2Info(t *testing.T, length int) (torrent.InfoDict, map[string][]byte) {
+	pieceLength := int64(2 * torrent.BlockSize)
+	hashes, err := torrent.HashFileV2(bytes.NewReader(make([]byte, length)), pieceLength)
+	assert.NoError(t, err)
+	info := torrent.InfoDict{
+		PieceLength: pieceLength,
+		Name:        "test",
+		MetaVersion: 2,
+		FileTree: torrent.FileTree{
+			"test": {File: &torrent.TreeFileInfo{Length: hashes.Length, PiecesRoot: hashes.PiecesRoot}},
+		},
+	}
+	return info, map[string][]byte{string(hashes.PiecesRoot): hashes.PieceLayer}
+}
+
+func TestNewTorrentTask_V2(t *testing.T) {
+	// 5 pieces, the last one short
+	info, layers := newV2Info(t, 9*torrent.BlockSize)
+	torrentFile := &torrent.TorrentFile{Info: info, PieceLayers: layers}
+
+	tt, err := NewTorrentTask(torrentFile)
+
+	assert.NoError(t, err)
+	assert.Len(t, tt.PieceStatus, 5)
+	assert.Len(t, tt.Availability, 5)
+}
+
+func TestNewTorrentTask_Hybrid(t *testing.T) {
+	info, layers := newV2Info(t, 9*torrent.BlockSize)
+	info.Pieces = make([]byte, 20*5)
+	info.Length = 9 * torrent.BlockSize
+	torrentFile := &torrent.TorrentFile{Info: info, PieceLayers: layers}
+
+	tt, err := NewTorrentTask(torrentFile)
+
+	assert.NoError(t, err)
+	assert.Len(t, tt.PieceStatus, 5)
+}
+
+func TestNewTorrentTask_V2MissingPieceLayers(t *testing.T) {
+	info, _ := newV2Info(t, 9*torrent.BlockSize)
+	torrentFile := &torrent.TorrentFile{Info: info}
+
+	tt, err := NewTorrentTask(torrentFile)
+
+	assert.Error(t, err)
+	assert.Nil(t, tt)
+}
+
 func TestAddPeer(t *testing.T) {
 	// Prepare the torrent task
 	torrentFile := &torrent.TorrentFile{
@@ -63,6 +115,8 @@ func TestAddPeer(t *testing.T) {
 	tt, err := NewTorrentTask(torrentFile)
 	assert.NoError(t, err)
 
+	assert.Equal(t, []string{"http://example.com/announce"}, tt.Trackers.Trackers())
+
 	peer1 := peer.Peer{IP: "192.168.1.1", Port: 6881}
 	tt.AddPeer(peer1)
 
@@ -206,3 +260,53 @@ func TestInvalidPieceStatusUpdate(t *testing.T) {
 	assert.Equal(t, 0.0, tt.Progress)
 	assert.Equal(t, StatusIdle, tt.Status)
 }
+
+func TestAddPeerFrom_Private(t *testing.T) {
+	private := int64(1)
+	torrentFile := &torrent.TorrentFile{
+		Announce: "http://example.com/announce",
+		Info: torrent.InfoDict{
+			PieceLength: 256,
+			Pieces:      make([]byte, 20),
+			Name:        "test.torrent",
+			Length:      256,
+			Private:     &private,
+		},
+	}
+
+	tt, err := NewTorrentTask(torrentFile)
+	assert.NoError(t, err)
+
+	for _, source := range []PeerSource{SourceDHT, SourcePEX, SourceLSD} {
+		assert.False(t, tt.AllowsPeerSource(source), source.String())
+		assert.Error(t, tt.AddPeerFrom(peer.Peer{IP: "10.0.0.1", Port: 6881}, source))
+	}
+	assert.Empty(t, tt.Peers)
+
+	assert.NoError(t, tt.AddPeerFrom(peer.Peer{IP: "10.0.0.2", Port: 6881}, SourceTracker))
+	assert.NoError(t, tt.AddPeerFrom(peer.Peer{IP: "10.0.0.3", Port: 6881}, SourceIncoming))
+	assert.Len(t, tt.Peers, 2)
+}
+
+func TestAddPeerFrom_Public(t *testing.T) {
+	public := int64(0)
+	torrentFile := &torrent.TorrentFile{
+		Announce: "http://example.com/announce",
+		Info: torrent.InfoDict{
+			PieceLength: 256,
+			Pieces:      make([]byte, 20),
+			Name:        "test.torrent",
+			Length:      256,
+			Private:     &public,
+		},
+	}
+
+	tt, err := NewTorrentTask(torrentFile)
+	assert.NoError(t, err)
+
+	for _, source := range []PeerSource{SourceTracker, SourceDHT, SourcePEX, SourceLSD, SourceIncoming} {
+		assert.True(t, tt.AllowsPeerSource(source), source.String())
+		assert.NoError(t, tt.AddPeerFrom(peer.Peer{IP: "10.0.0.1", Port: 6881}, source))
+	}
+	assert.Len(t, tt.Peers, 5)
+}
@@ -0,0 +1,51 @@
+package torrent
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The BEP 47 file attributes, each a letter of File.Attr. Readers ignore
+// letters they do not know.
+const (
+	AttrPadding    = 'p' // the file only fills the rest of a piece with zeros
+	AttrHidden     = 'h'
+	AttrExecutable = 'x'
+	AttrSymlink    = 'l' // the file is a link to SymlinkPath and has no data
+)
+
+// legacyPaddingPrefix starts the names of the padding files BitComet made
+// before BEP 47, which carry no attr.
+const legacyPaddingPrefix = "_____padding_file_"
+
+// HasAttr reports whether the file has the attribute.
+func (f *File) HasAttr(attr rune) bool {
+	return strings.ContainsRune(f.Attr, attr)
+}
+
+// IsPadding reports whether the file is padding, whose zeros are part of
+// the pieces but which is never stored.
+func (f *File) IsPadding() bool {
+	if f.HasAttr(AttrPadding) {
+		return true
+	}
+	return len(f.Path) > 0 && strings.HasPrefix(f.Path[len(f.Path)-1], legacyPaddingPrefix)
+}
+
+func (f *File) IsHidden() bool {
+	return f.HasAttr(AttrHidden)
+}
+
+func (f *File) IsExecutable() bool {
+	return f.HasAttr(AttrExecutable)
+}
+
+func (f *File) IsSymlink() bool {
+	return f.HasAttr(AttrSymlink)
+}
+
+// paddingFile returns a padding file of length bytes, named as BEP 47
+// suggests so that padding files of the same length share a name.
+func paddingFile(length int64) File {
+	return File{Length: length, Path: []string{".pad", strconv.FormatInt(length, 10)}, Attr: string(AttrPadding)}
+}
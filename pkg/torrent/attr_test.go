@@ -0,0 +1,53 @@
+package torrent
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"torrent/pkg/bencoder"
+)
+
+func TestFile_Attributes(t *testing.T) {
+	tests := []struct {
+		file                                 File
+		padding, hidden, executable, symlink bool
+	}{
+		{file: File{Path: []string{"a"}}},
+		{file: File{Path: []string{".pad", "10"}, Attr: "p"}, padding: true},
+		{file: File{Path: []string{"run.sh"}, Attr: "xh"}, hidden: true, executable: true},
+		{file: File{Path: []string{"link"}, Attr: "l", SymlinkPath: []string{"a"}}, symlink: true},
+		{file: File{Path: []string{"_____padding_file_0_if you see this file, please update to BitComet 0.85 or above____"}}, padding: true},
+		{file: File{Path: []string{"a"}, Attr: "z"}},
+	}
+	for _, tt := range tests {
+		file := tt.file
+		got := [4]bool{file.IsPadding(), file.IsHidden(), file.IsExecutable(), file.IsSymlink()}
+		if want := [4]bool{tt.padding, tt.hidden, tt.executable, tt.symlink}; got != want {
+			t.Errorf("attributes of %+v = %v, expected %v", file, got, want)
+		}
+	}
+}
+
+func TestFile_AttributesRoundTrip(t *testing.T) {
+	data := []byte("d5:filesld4:attr1:x6:lengthi3e4:pathl3:runeed4:attr1:p6:lengthi13e4:pathl4:.pad2:13eed4:attr1:l6:lengthi0e4:pathl4:linke12:symlink pathl3:runeee" +
+		"4:name1:d12:piece lengthi16e6:pieces20:" + string(make([]byte, 20)) + "e")
+	var info InfoDict
+	if err := bencoder.NewDecoder(bytes.NewReader(data)).Decode(&info); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	want := []File{
+		{Length: 3, Path: []string{"run"}, Attr: "x"},
+		paddingFile(13),
+		{Length: 0, Path: []string{"link"}, Attr: "l", SymlinkPath: []string{"run"}},
+	}
+	if !reflect.DeepEqual(info.Files, want) {
+		t.Errorf("Files = %+v, expected %+v", info.Files, want)
+	}
+	encoded, err := bencoder.Marshal(&info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("Marshal mismatch. Got %q, expected %q", encoded, data)
+	}
+}
@@ -0,0 +1,299 @@
+package torrent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	minPieceLength = 16 << 10
+	maxPieceLength = 16 << 20
+	targetPieces   = 2000
+)
+
+// Builder creates v1 torrents from files on disk.
+type Builder struct {
+	// Trackers are the announce URLs in tiers. The first URL is the
+	// announce; with more than one, all tiers go in the announce-list.
+	Trackers     [][]string
+	WebSeeds     []string
+	Comment      string
+	CreatedBy    string
+	CreationDate time.Time // the current time when zero
+	Private      bool
+	Source       string
+
+	// Align pads the files of a multi-file torrent with BEP 47 padding
+	// files so that each starts on a piece boundary.
+	Align bool
+
+	// PieceLength is the size of the pieces, chosen from the size of the
+	// content when zero.
+	PieceLength int64
+
+	// Workers is the number of goroutines hashing pieces, the number of
+	// CPUs when zero.
+	Workers int
+
+	// Progress, when set, is called as data is hashed with the number of
+	// bytes done so far out of the total.
+	Progress func(done, total int64)
+}
+
+// builderFile is a file to be added to a torrent.
+type builderFile struct {
+	osPath  string
+	path    []string
+	length  int64
+	padding bool // zeros, read from no file
+}
+
+// Build creates a torrent of the file or directory at path. A directory
+// becomes a multi-file torrent of every regular file below it, in lexical
+// order; the pieces run across file boundaries.
+func (b *Builder) Build(path string) (*TorrentFile, error) {
+	return b.BuildContext(context.Background(), path)
+}
+
+// BuildContext is Build, stopping early if ctx is cancelled.
+func (b *Builder) BuildContext(ctx context.Context, path string) (*TorrentFile, error) {
+	// resolve the path so that "." and ".." are named after the directory
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Dir(path) == path {
+		return nil, fmt.Errorf("cannot build a torrent of the root directory %s, which has no name", path)
+	}
+	files, err := collectFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, file := range files {
+		total += file.length
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no data in %s", path)
+	}
+
+	pieceLength := b.PieceLength
+	if pieceLength == 0 {
+		pieceLength = DefaultPieceLength(total)
+	}
+	if pieceLength <= 0 {
+		return nil, fmt.Errorf("invalid piece length %d", pieceLength)
+	}
+	if b.Align && files[0].path != nil {
+		files = alignFiles(files, pieceLength)
+		total = 0
+		for _, file := range files {
+			total += file.length
+		}
+	}
+
+	pieces, err := b.hashFiles(ctx, files, pieceLength, total)
+	if err != nil {
+		return nil, err
+	}
+
+	torrent := &TorrentFile{
+		Comment:   b.Comment,
+		CreatedBy: b.CreatedBy,
+		URLList:   b.WebSeeds,
+		Info: InfoDict{
+			PieceLength: pieceLength,
+			Pieces:      pieces,
+			Name:        filepath.Base(path),
+			Source:      b.Source,
+		},
+	}
+	if b.Private {
+		private := int64(1)
+		torrent.Info.Private = &private
+	}
+	if len(files) == 1 && files[0].path == nil {
+		torrent.Info.Length = files[0].length
+	} else {
+		for _, file := range files {
+			if file.padding {
+				torrent.Info.Files = append(torrent.Info.Files, paddingFile(file.length))
+				continue
+			}
+			torrent.Info.Files = append(torrent.Info.Files, File{Length: file.length, Path: file.path})
+		}
+	}
+
+	torrent.CreationDate = b.CreationDate.Unix()
+	if b.CreationDate.IsZero() {
+		torrent.CreationDate = time.Now().Unix()
+	}
+	b.setTrackers(torrent)
+	return torrent, nil
+}
+
+// DefaultPieceLength picks a piece length for content of total bytes: the
+// smallest power of two giving no more than about 2000 pieces, kept between
+// 16 KiB and 16 MiB.
+func DefaultPieceLength(total int64) int64 {
+	pieceLength := int64(minPieceLength)
+	for pieceLength < maxPieceLength && total/pieceLength > targetPieces {
+		pieceLength *= 2
+	}
+	return pieceLength
+}
+
+// collectFiles lists the regular files at path. A single file has no path
+// within the torrent.
+func collectFiles(root string) ([]builderFile, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().IsRegular() {
+		return []builderFile{{osPath: root, length: info.Size()}}, nil
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a regular file or directory", root)
+	}
+
+	var files []builderFile
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		// follow links to files, but not to directories
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, builderFile{
+			osPath: path,
+			path:   strings.Split(filepath.ToSlash(relative), "/"),
+			length: info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files in %s", root)
+	}
+	return files, nil
+}
+
+// alignFiles adds a padding file after each file but the last that does
+// not end on a piece boundary.
+func alignFiles(files []builderFile, pieceLength int64) []builderFile {
+	var aligned []builderFile
+	for i, file := range files {
+		aligned = append(aligned, file)
+		if rest := file.length % pieceLength; rest != 0 && i < len(files)-1 {
+			padding := pieceLength - rest
+			aligned = append(aligned, builderFile{path: paddingFile(padding).Path, length: padding, padding: true})
+		}
+	}
+	return aligned
+}
+
+// hashFiles hashes the files as one stream, in order.
+func (b *Builder) hashFiles(ctx context.Context, files []builderFile, pieceLength, total int64) ([]byte, error) {
+	hasher := ParallelHasher{PieceLength: pieceLength, Workers: b.Workers}
+	if b.Progress != nil {
+		hasher.Progress = func(done int64) {
+			b.Progress(done, total)
+		}
+	}
+	r := &filesReader{files: files}
+	defer r.Close()
+	return hasher.Hash(ctx, r)
+}
+
+func (b *Builder) setTrackers(torrent *TorrentFile) {
+	var tiers [][]string
+	for _, tier := range b.Trackers {
+		if len(tier) > 0 {
+			tiers = append(tiers, tier)
+		}
+	}
+	if len(tiers) == 0 {
+		return
+	}
+	torrent.Announce = tiers[0][0]
+	if len(tiers) > 1 || len(tiers[0]) > 1 {
+		torrent.AnnounceList = tiers
+	}
+}
+
+// filesReader reads files one after another, and zeros in place of padding,
+// checking each file has the length it had when listed.
+type filesReader struct {
+	files   []builderFile
+	current *os.File
+	read    int64
+}
+
+func (r *filesReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.files) == 0 {
+				return 0, io.EOF
+			}
+			if file := r.files[0]; file.padding {
+				n := len(p)
+				if rest := file.length - r.read; int64(n) >= rest {
+					n = int(rest)
+					r.files, r.read = r.files[1:], 0
+				} else {
+					r.read += int64(n)
+				}
+				for i := range p[:n] {
+					p[i] = 0
+				}
+				return n, nil
+			}
+			f, err := os.Open(r.files[0].osPath)
+			if err != nil {
+				return 0, err
+			}
+			r.current = f
+		}
+
+		n, err := r.current.Read(p)
+		r.read += int64(n)
+		file := r.files[0]
+		if r.read > file.length || err == io.EOF && r.read != file.length {
+			return n, fmt.Errorf("%s changed size while hashing", file.osPath)
+		}
+		if err != io.EOF {
+			return n, err
+		}
+		r.current.Close()
+		r.current, r.read = nil, 0
+		r.files = r.files[1:]
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *filesReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
@@ -0,0 +1,226 @@
+package torrent
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"torrent/pkg/bencoder"
+)
+
+func writeFiles(t *testing.T, root string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuilder_Directory(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "release")
+	files := map[string][]byte{
+		"b.bin":       testContent(1000, 1),
+		"a/one.txt":   testContent(300, 2),
+		"a/two.txt":   testContent(5, 3),
+		"a/empty.txt": nil,
+	}
+	writeFiles(t, root, files)
+
+	var progress []int64
+	builder := Builder{
+		Trackers:     [][]string{{"http://a/announce"}, {"http://b/announce", "http://c/announce"}},
+		WebSeeds:     []string{"http://seed/"},
+		Comment:      "comment",
+		CreatedBy:    "go-torrent",
+		CreationDate: time.Unix(1700000000, 0),
+		Private:      true,
+		Source:       "SRC",
+		PieceLength:  256,
+		Progress: func(done, total int64) {
+			if total != 1305 {
+				t.Errorf("progress total = %d, expected 1305", total)
+			}
+			progress = append(progress, done)
+		},
+	}
+	torrent, err := builder.Build(root)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	wantFiles := []File{
+		{Length: 0, Path: []string{"a", "empty.txt"}},
+		{Length: 300, Path: []string{"a", "one.txt"}},
+		{Length: 5, Path: []string{"a", "two.txt"}},
+		{Length: 1000, Path: []string{"b.bin"}},
+	}
+	if !reflect.DeepEqual(torrent.Info.Files, wantFiles) {
+		t.Errorf("Files = %+v, expected %+v", torrent.Info.Files, wantFiles)
+	}
+	// the pieces run across file boundaries
+	content := bytes.Join([][]byte{files["a/one.txt"], files["a/two.txt"], files["b.bin"]}, nil)
+	if want := GeneratePieces(content, 256); string(torrent.Info.Pieces) != want {
+		t.Errorf("Pieces = %x, expected %x", torrent.Info.Pieces, want)
+	}
+	if torrent.Info.Name != "release" || torrent.Info.Length != 0 {
+		t.Errorf("Name = %q, Length = %d", torrent.Info.Name, torrent.Info.Length)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 1305 {
+		t.Errorf("progress = %v, expected to end at 1305", progress)
+	}
+
+	data, err := bencoder.NewSimpleBencoder().Marshal(torrent)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	loaded, err := NewTorrentFromBencode(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if loaded.Announce != "http://a/announce" || len(loaded.AnnounceList) != 2 || loaded.CreationDate != 1700000000 {
+		t.Errorf("trackers or date not kept: %+v", loaded)
+	}
+	if !loaded.Info.IsPrivate() || loaded.Info.Source != "SRC" {
+		t.Errorf("IsPrivate() = %v, Source = %q", loaded.Info.IsPrivate(), loaded.Info.Source)
+	}
+	if !reflect.DeepEqual(loaded.URLList, URLList{"http://seed/"}) {
+		t.Errorf("URLList = %q", loaded.URLList)
+	}
+}
+
+func TestBuilder_SingleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.bin")
+	data := testContent(40000, 4)
+	writeFiles(t, filepath.Dir(path), map[string][]byte{"file.bin": data})
+
+	torrent, err := (&Builder{Trackers: [][]string{{"http://a/announce"}}}).Build(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if torrent.Info.Name != "file.bin" || torrent.Info.Length != 40000 || torrent.Info.Files != nil {
+		t.Errorf("Info = %+v", torrent.Info)
+	}
+	if torrent.Info.PieceLength != 16<<10 {
+		t.Errorf("PieceLength = %d, expected the minimum", torrent.Info.PieceLength)
+	}
+	if want := GeneratePieces(data, 16<<10); string(torrent.Info.Pieces) != want {
+		t.Errorf("Pieces = %x, expected %x", torrent.Info.Pieces, want)
+	}
+	if torrent.AnnounceList != nil || torrent.Info.Private != nil || torrent.URLList != nil {
+		t.Errorf("unexpected optional keys: %+v", torrent)
+	}
+}
+
+func TestBuilder_Align(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "aligned")
+	files := map[string][]byte{
+		"a": testContent(20, 1),
+		"b": testContent(32, 2),
+		"c": testContent(5, 3),
+	}
+	writeFiles(t, root, files)
+
+	torrent, err := (&Builder{PieceLength: 16, Align: true}).Build(root)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	wantFiles := []File{
+		{Length: 20, Path: []string{"a"}},
+		paddingFile(12),
+		{Length: 32, Path: []string{"b"}},
+		{Length: 5, Path: []string{"c"}},
+	}
+	if !reflect.DeepEqual(torrent.Info.Files, wantFiles) {
+		t.Errorf("Files = %+v, expected %+v", torrent.Info.Files, wantFiles)
+	}
+	content := bytes.Join([][]byte{files["a"], make([]byte, 12), files["b"], files["c"]}, nil)
+	if want := GeneratePieces(content, 16); string(torrent.Info.Pieces) != want {
+		t.Errorf("Pieces = %x, expected %x", torrent.Info.Pieces, want)
+	}
+	if problems := torrent.Validate(); len(problems.Errors()) != 0 {
+		t.Errorf("Validate() = %v", problems)
+	}
+	// the padding is not part of the content
+	if got := torrent.Info.TotalLength(); got != 57 {
+		t.Errorf("TotalLength() = %d, expected 57", got)
+	}
+	if magnet, err := torrent.Magnet(); err != nil || magnet.Length != 57 {
+		t.Errorf("Magnet() = %+v, %v, expected a length of 57", magnet, err)
+	}
+}
+
+func TestBuilder_CurrentDirectory(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "here")
+	writeFiles(t, root, map[string][]byte{"a": testContent(100, 1)})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	for _, path := range []string{".", "./", filepath.Join("..", "here")} {
+		torrent, err := new(Builder).Build(path)
+		if err != nil {
+			t.Fatalf("Build(%q) error = %v", path, err)
+		}
+		if torrent.Info.Name != "here" {
+			t.Errorf("Build(%q) Name = %q, expected here", path, torrent.Info.Name)
+		}
+		if problems := torrent.Validate(); len(problems.Errors()) != 0 {
+			t.Errorf("Build(%q) Validate() = %v", path, problems)
+		}
+	}
+}
+
+func TestBuilder_RootDirectory(t *testing.T) {
+	root, err := filepath.Abs(string(filepath.Separator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = new(Builder).Build(root)
+	if err == nil || !strings.Contains(err.Error(), "root directory") {
+		t.Errorf("Build(%s) error = %v, expected a root directory error", root, err)
+	}
+}
+
+func TestBuilder_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, "empty"), map[string][]byte{"zero": nil})
+	if err := os.Mkdir(filepath.Join(dir, "nothing"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(dir, "missing"), filepath.Join(dir, "empty"), filepath.Join(dir, "nothing")} {
+		if _, err := new(Builder).Build(path); err == nil {
+			t.Errorf("Build(%s) succeeded", path)
+		}
+	}
+}
+
+func TestDefaultPieceLength(t *testing.T) {
+	tests := []struct {
+		total int64
+		want  int64
+	}{
+		{0, 16 << 10},
+		{2000 * 16 << 10, 16 << 10},
+		{2001 * 16 << 10, 32 << 10},
+		{1 << 30, 1 << 20},
+		{1 << 50, 16 << 20},
+	}
+	for _, tt := range tests {
+		if got := DefaultPieceLength(tt.total); got != tt.want {
+			t.Errorf("DefaultPieceLength(%d) = %d, expected %d", tt.total, got, tt.want)
+		}
+	}
+}
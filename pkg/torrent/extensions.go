@@ -0,0 +1,95 @@
+package torrent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"torrent/pkg/bencoder"
+	"unicode/utf8"
+)
+
+// URLList is the list of web seed URLs. Torrents with a single web seed
+// often store it as a plain string, which is read as a list of one; an
+// empty string is read as no web seeds.
+type URLList []string
+
+func (l *URLList) UnmarshalBencode(data []byte) error {
+	decoder := bencoder.NewDecoder(bytes.NewReader(data))
+	if len(data) > 0 && data[0] != 'l' {
+		var url string
+		if err := decoder.Decode(&url); err != nil {
+			return err
+		}
+		*l = nil
+		if url != "" {
+			*l = URLList{url}
+		}
+		return nil
+	}
+	var urls []string
+	if err := decoder.Decode(&urls); err != nil {
+		return err
+	}
+	*l = urls
+	return nil
+}
+
+// Node is a DHT node to bootstrap from, encoded as a [host, port] list.
+type Node struct {
+	Host string
+	Port int
+}
+
+func (n Node) MarshalBencode() ([]byte, error) {
+	return bencoder.Marshal([]interface{}{n.Host, n.Port})
+}
+
+func (n *Node) UnmarshalBencode(data []byte) error {
+	var pair []bencoder.RawMessage
+	if err := bencoder.NewDecoder(bytes.NewReader(data)).Decode(&pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("node must be a [host, port] list, not %d elements", len(pair))
+	}
+	if err := bencoder.NewDecoder(bytes.NewReader(pair[0])).Decode(&n.Host); err != nil {
+		return err
+	}
+	if err := bencoder.NewDecoder(bytes.NewReader(pair[1])).Decode(&n.Port); err != nil {
+		return err
+	}
+	if n.Port < 0 || n.Port > 65535 {
+		return errors.New("node port out of range")
+	}
+	return nil
+}
+
+// IsPrivate reports whether the torrent is private, so peers may only be
+// found through its trackers: not through DHT, PEX or local discovery.
+// Only a private value of 1 makes a torrent private.
+func (info *InfoDict) IsPrivate() bool {
+	return info.Private != nil && *info.Private == 1
+}
+
+// DisplayName returns name.utf-8 when it is present and valid, and name
+// otherwise.
+func (info *InfoDict) DisplayName() string {
+	if info.NameUTF8 != "" && utf8.ValidString(info.NameUTF8) {
+		return info.NameUTF8
+	}
+	return info.Name
+}
+
+// DisplayPath returns path.utf-8 when it is present and valid, and path
+// otherwise.
+func (f *File) DisplayPath() []string {
+	if len(f.PathUTF8) == 0 {
+		return f.Path
+	}
+	for _, element := range f.PathUTF8 {
+		if !utf8.ValidString(element) {
+			return f.Path
+		}
+	}
+	return f.PathUTF8
+}
@@ -0,0 +1,180 @@
+package torrent
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"torrent/pkg/bencoder"
+)
+
+func TestURLList_Unmarshal(t *testing.T) {
+	tests := []struct {
+		data string
+		want URLList
+	}{
+		{"d4:infod4:name1:a12:piece lengthi16ee8:url-list9:http://a/e", URLList{"http://a/"}},
+		{"d4:infod4:name1:a12:piece lengthi16ee8:url-listl9:http://a/9:http://b/ee", URLList{"http://a/", "http://b/"}},
+		{"d4:infod4:name1:a12:piece lengthi16ee8:url-listlee", URLList{}},
+		{"d4:infod4:name1:a12:piece lengthi16ee8:url-list0:e", nil},
+	}
+	for _, tt := range tests {
+		torrent, err := NewTorrentFromBencode([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(torrent.URLList, tt.want) {
+			t.Errorf("URLList of %q = %q, expected %q", tt.data, torrent.URLList, tt.want)
+		}
+	}
+
+	if _, err := NewTorrentFromBencode([]byte("d4:infod4:name1:a12:piece lengthi16ee8:url-listi1ee")); err == nil {
+		t.Errorf("expected an error for an integer url-list")
+	}
+}
+
+func TestNode_Unmarshal(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Node
+		wantErr bool
+	}{
+		{data: "l11:router.host4:6881e", wantErr: true},
+		{data: "l11:router.hosti6881ee", want: Node{Host: "router.host", Port: 6881}},
+		{data: "l11:router.hostei6881ee", wantErr: true},
+		{data: "l11:router.hosti6881ei1ee", wantErr: true},
+		{data: "l11:router.hosti70000ee", wantErr: true},
+		{data: "11:router.host", wantErr: true},
+	}
+	for _, tt := range tests {
+		var node Node
+		err := node.UnmarshalBencode([]byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("UnmarshalBencode(%q) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && node != tt.want {
+			t.Errorf("UnmarshalBencode(%q) = %+v, expected %+v", tt.data, node, tt.want)
+		}
+	}
+}
+
+func TestNode_ErrorLocation(t *testing.T) {
+	data := []byte("d4:infod4:name1:a12:piece lengthi16ee5:nodesll1:hi1eel1:hi99999eeee")
+	_, err := NewTorrentFromBencode(data)
+	var unmarshalerErr *bencoder.UnmarshalerError
+	if !errors.As(err, &unmarshalerErr) || unmarshalerErr.Path != "nodes[1]" {
+		t.Errorf("NewTorrentFromBencode() error = %v, expected one at nodes[1]", err)
+	}
+}
+
+func TestOptionalFields_RoundTrip(t *testing.T) {
+	// every optional key, in canonical order, including an explicit private=0
+	data := []byte("d8:announce3:url7:comment2:hi9:httpseedsl9:http://h/e" +
+		"4:infod11:collectionsl1:ce5:filesld6:lengthi5e6:md5sum2:ff4:pathl1:ae10:path.utf-8l1:Aeee" +
+		"4:name1:n10:name.utf-81:N12:piece lengthi16e6:pieces20:" + string(make([]byte, 20)) +
+		"7:privatei0e7:similarl20:" + string(bytes.Repeat([]byte{7}, 20)) + "e6:source1:se" +
+		"5:nodesll4:hosti1eee8:url-listl9:http://w/ee")
+	torrent, err := NewTorrentFromBencode(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	info := torrent.Info
+	if info.Private == nil || info.IsPrivate() {
+		t.Errorf("Private = %v, expected an explicit 0", info.Private)
+	}
+	if info.Source != "s" || info.NameUTF8 != "N" || !reflect.DeepEqual(info.Collections, []string{"c"}) || len(info.Similar) != 1 {
+		t.Errorf("Info = %+v", info)
+	}
+	if file := info.Files[0]; file.MD5Sum != "ff" || !reflect.DeepEqual(file.PathUTF8, []string{"A"}) {
+		t.Errorf("Files[0] = %+v", file)
+	}
+	if !reflect.DeepEqual(torrent.Nodes, []Node{{Host: "host", Port: 1}}) {
+		t.Errorf("Nodes = %+v", torrent.Nodes)
+	}
+	if !reflect.DeepEqual(torrent.HTTPSeeds, []string{"http://h/"}) || !reflect.DeepEqual(torrent.URLList, URLList{"http://w/"}) {
+		t.Errorf("HTTPSeeds = %q, URLList = %q", torrent.HTTPSeeds, torrent.URLList)
+	}
+	if torrent.Extra != nil || info.Extra != nil {
+		t.Errorf("unexpected extra keys: %q, %q", torrent.Extra, info.Extra)
+	}
+
+	// a torrent built in code encodes the same way as the one read
+	built := TorrentFile{
+		Announce:  torrent.Announce,
+		Comment:   torrent.Comment,
+		HTTPSeeds: torrent.HTTPSeeds,
+		Nodes:     torrent.Nodes,
+		URLList:   torrent.URLList,
+		Info:      torrent.Info,
+	}
+	encoded, err := bencoder.NewSimpleBencoder().Marshal(&built)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("Marshal mismatch. Got %q, expected %q", encoded, data)
+	}
+}
+
+func TestPrivate_OtherValues(t *testing.T) {
+	tests := []struct {
+		private     string
+		wantPrivate bool
+	}{
+		{"i0e", false},
+		{"i1e", true},
+		{"i2e", false},
+		{"i-1e", false},
+	}
+	for _, tt := range tests {
+		data := []byte("d4:infod4:name1:a12:piece lengthi16e7:private" + tt.private + "ee")
+		torrent, err := NewTorrentFromBencode(data)
+		if err != nil {
+			t.Fatalf("private %s: expected no error, got: %v", tt.private, err)
+		}
+		if got := torrent.Info.IsPrivate(); got != tt.wantPrivate {
+			t.Errorf("private %s: IsPrivate() = %v, expected %v", tt.private, got, tt.wantPrivate)
+		}
+		// the value is written back as it was, keeping the info hash
+		encoded, err := bencoder.NewSimpleBencoder().Marshal(&TorrentFile{Info: torrent.Info})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if !bytes.Equal(encoded, data) {
+			t.Errorf("Marshal mismatch. Got %q, expected %q", encoded, data)
+		}
+	}
+}
+
+func TestDisplayNames(t *testing.T) {
+	info := InfoDict{
+		Name:        "name",
+		NameUTF8:    "名前",
+		PieceLength: 16,
+		Pieces:      make([]byte, 20),
+		Files: []File{
+			{Length: 4, Path: []string{"plain"}, PathUTF8: []string{"ünïcode"}},
+			{Length: 4, Path: []string{"fallback"}, PathUTF8: []string{"bad\xff"}},
+			{Length: 4, Path: []string{"only"}},
+		},
+	}
+	layout, err := NewLayout(&info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var got [][]string
+	for _, file := range layout.Files {
+		got = append(got, file.Path)
+	}
+	want := [][]string{{"名前", "ünïcode"}, {"名前", "fallback"}, {"名前", "only"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("layout paths = %q, expected %q", got, want)
+	}
+
+	info.NameUTF8 = "\xfe"
+	if name := info.DisplayName(); name != "name" {
+		t.Errorf("DisplayName() with invalid name.utf-8 = %q, expected name", name)
+	}
+}
@@ -0,0 +1,132 @@
+package torrent
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ParallelHasher computes v1 piece hashes on several goroutines. Data is
+// read sequentially, so it suits a single stream spanning many files, and
+// the hashes come out in piece order.
+type ParallelHasher struct {
+	PieceLength int64
+
+	// Workers is the number of goroutines hashing pieces, the number of
+	// CPUs when zero. At most Workers+2 pieces are held in memory.
+	Workers int
+
+	// Progress, when set, is called on the calling goroutine with the
+	// number of bytes hashed so far, counting only whole runs of pieces
+	// from the start.
+	Progress func(done int64)
+}
+
+// hashJob is one piece on its way through the workers.
+type hashJob struct {
+	data []byte
+	sum  [sha1.Size]byte
+	done chan struct{}
+}
+
+// Hash reads r to its end and returns the concatenated SHA-1 hashes of its
+// pieces, the last of which may be short. If ctx is cancelled, Hash stops
+// once any Read in progress returns and reports ctx.Err().
+func (h *ParallelHasher) Hash(ctx context.Context, r io.Reader) ([]byte, error) {
+	if h.PieceLength <= 0 {
+		return nil, errors.New("piece length must be positive")
+	}
+	workers := h.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	// stop the goroutines on any return, then wait for them
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffers bounds the pieces in flight; ordered carries every job to
+	// the collector below in the order it was read
+	buffers := make(chan []byte, workers+2)
+	for i := 0; i < cap(buffers); i++ {
+		buffers <- nil
+	}
+	jobs := make(chan *hashJob)
+	ordered := make(chan *hashJob, cap(buffers))
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.sum = sha1.Sum(job.data)
+				close(job.done)
+			}
+		}()
+	}
+
+	var readErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(ordered)
+		defer close(jobs)
+		for {
+			var buffer []byte
+			select {
+			case buffer = <-buffers:
+			case <-ctx.Done():
+				return
+			}
+			if buffer == nil {
+				buffer = make([]byte, h.PieceLength)
+			}
+
+			n, err := io.ReadFull(r, buffer)
+			if n > 0 {
+				job := &hashJob{data: buffer[:n], done: make(chan struct{})}
+				ordered <- job // never blocks: it holds as many jobs as there are buffers
+				select {
+				case jobs <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				readErr = err
+				return
+			}
+		}
+	}()
+
+	var pieces []byte
+	var done int64
+	for job := range ordered {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		pieces = append(pieces, job.sum[:]...)
+		done += int64(len(job.data))
+		buffers <- job.data[:cap(job.data)]
+		if h.Progress != nil {
+			h.Progress(done)
+		}
+	}
+	// the reader has finished, so its error is safe to read
+	if readErr != nil {
+		return nil, readErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return pieces, nil
+}
@@ -0,0 +1,93 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParallelHasher_MatchesGeneratePieces(t *testing.T) {
+	data := testContent(100*64+13, 1)
+	tests := []struct {
+		name    string
+		reader  func() io.Reader
+		workers int
+	}{
+		{"one worker", func() io.Reader { return bytes.NewReader(data) }, 1},
+		{"many workers", func() io.Reader { return bytes.NewReader(data) }, 8},
+		{"short reads", func() io.Reader { return iotest.HalfReader(bytes.NewReader(data)) }, 3},
+		{"default workers", func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progress []int64
+			hasher := ParallelHasher{
+				PieceLength: 64,
+				Workers:     tt.workers,
+				Progress:    func(done int64) { progress = append(progress, done) },
+			}
+			pieces, err := hasher.Hash(context.Background(), tt.reader())
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if want := GeneratePieces(data, 64); string(pieces) != want {
+				t.Errorf("Hash() = %x, expected %x", pieces, want)
+			}
+			if len(progress) != 101 || progress[0] != 64 || progress[100] != int64(len(data)) {
+				t.Errorf("progress = %v", progress)
+			}
+		})
+	}
+}
+
+func TestParallelHasher_Empty(t *testing.T) {
+	pieces, err := (&ParallelHasher{PieceLength: 64}).Hash(context.Background(), bytes.NewReader(nil))
+	if err != nil || pieces != nil {
+		t.Errorf("Hash(empty) = %x, %v", pieces, err)
+	}
+}
+
+func TestParallelHasher_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	hasher := ParallelHasher{
+		PieceLength: 64,
+		Workers:     2,
+		Progress: func(done int64) {
+			if done >= 10*64 {
+				cancel()
+			}
+		},
+	}
+	// an endless reader: only cancelling ends the hashing
+	_, err := hasher.Hash(ctx, iotest.HalfReader(zeroReader{}))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Hash() error = %v, expected %v", err, context.Canceled)
+	}
+
+	if _, err := hasher.Hash(ctx, bytes.NewReader(make([]byte, 100))); !errors.Is(err, context.Canceled) {
+		t.Errorf("Hash() with a cancelled context error = %v", err)
+	}
+}
+
+func TestParallelHasher_ReadError(t *testing.T) {
+	failure := errors.New("disk on fire")
+	r := io.MultiReader(bytes.NewReader(make([]byte, 1000)), iotest.ErrReader(failure))
+	if _, err := (&ParallelHasher{PieceLength: 64}).Hash(context.Background(), r); !errors.Is(err, failure) {
+		t.Errorf("Hash() error = %v, expected %v", err, failure)
+	}
+	if _, err := (&ParallelHasher{}).Hash(context.Background(), r); err == nil {
+		t.Errorf("expected an error for a zero piece length")
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
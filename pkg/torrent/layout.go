@@ -0,0 +1,134 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"sort"
+)
+
+// Layout places the files of a torrent in its pieces. The pieces cover the
+// files as one stream of bytes in order. In a v2 torrent every file starts
+// on a piece boundary, so the stream has gaps.
+type Layout struct {
+	PieceLength int64
+	NumPieces   int
+	Files       []LayoutFile
+}
+
+// LayoutFile is a file and where it lies in the stream of pieces.
+type LayoutFile struct {
+	Path   []string // including the torrent name, the directory of a multi-file torrent; UTF-8 variants preferred
+	Length int64
+	Offset int64
+
+	// Padding files hold zeros that are hashed but never stored.
+	Padding bool
+}
+
+// FileSpan is the part of a file covered by a piece.
+type FileSpan struct {
+	File   int   // index in Layout.Files
+	Offset int64 // position within the file
+	Length int64
+}
+
+// NewLayout computes the layout of the content described by info, using
+// its v1 file list unless it is a v2-only torrent.
+func NewLayout(info *InfoDict) (*Layout, error) {
+	if info.PieceLength <= 0 {
+		return nil, fmt.Errorf("invalid piece length %d", info.PieceLength)
+	}
+	layout := &Layout{PieceLength: info.PieceLength}
+
+	var offset int64
+	add := func(path []string, length int64, padding bool) error {
+		if length < 0 {
+			return fmt.Errorf("file %q has negative length %d", path, length)
+		}
+		layout.Files = append(layout.Files, LayoutFile{Path: path, Length: length, Offset: offset, Padding: padding})
+		offset += length
+		return nil
+	}
+
+	if info.Version() == V2 {
+		for _, file := range info.FileTree.Files() {
+			if err := add(append([]string{info.DisplayName()}, file.Path...), file.Length, false); err != nil {
+				return nil, err
+			}
+			// the next file starts a new piece
+			if rest := offset % info.PieceLength; rest != 0 {
+				offset += info.PieceLength - rest
+			}
+		}
+		layout.NumPieces = int(offset / info.PieceLength)
+		return layout, nil
+	}
+
+	if len(info.Files) == 0 {
+		if err := add([]string{info.DisplayName()}, info.Length, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range info.Files {
+		if err := add(append([]string{info.DisplayName()}, file.DisplayPath()...), file.Length, file.IsPadding()); err != nil {
+			return nil, err
+		}
+	}
+	layout.NumPieces = int((offset + info.PieceLength - 1) / info.PieceLength)
+	if len(info.Pieces) != layout.NumPieces*sha1.Size {
+		return nil, fmt.Errorf("pieces hold %d bytes of hashes for %d pieces", len(info.Pieces), layout.NumPieces)
+	}
+	return layout, nil
+}
+
+// PieceSpans returns the parts of files the piece at index covers, in
+// order. Zero-length files are never part of a piece.
+func (l *Layout) PieceSpans(index int) []FileSpan {
+	if index < 0 || index >= l.NumPieces {
+		return nil
+	}
+	start := int64(index) * l.PieceLength
+	end := start + l.PieceLength
+
+	var spans []FileSpan
+	// the first file ending after the piece starts
+	first := sort.Search(len(l.Files), func(i int) bool {
+		return l.Files[i].Offset+l.Files[i].Length > start
+	})
+	for i := first; i < len(l.Files) && l.Files[i].Offset < end; i++ {
+		file := l.Files[i]
+		from, to := start, end
+		if file.Offset > from {
+			from = file.Offset
+		}
+		if fileEnd := file.Offset + file.Length; fileEnd < to {
+			to = fileEnd
+		}
+		if to > from {
+			spans = append(spans, FileSpan{File: i, Offset: from - file.Offset, Length: to - from})
+		}
+	}
+	return spans
+}
+
+// PieceSize returns the number of bytes in the piece at index: the piece
+// length for all but the last piece of the torrent, or of a v2 file.
+func (l *Layout) PieceSize(index int) int64 {
+	var size int64
+	for _, span := range l.PieceSpans(index) {
+		size += span.Length
+	}
+	return size
+}
+
+// FilePieces returns the range of pieces, first up to but not including
+// end, holding the file at index. A zero-length file has an empty range.
+func (l *Layout) FilePieces(index int) (first, end int) {
+	file := l.Files[index]
+	first = int(file.Offset / l.PieceLength)
+	if file.Length == 0 {
+		return first, first
+	}
+	end = int((file.Offset + file.Length + l.PieceLength - 1) / l.PieceLength)
+	return first, end
+}
@@ -0,0 +1,110 @@
+package torrent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayout_MultiFile(t *testing.T) {
+	// 10 + 0 + 25 + 5 bytes in pieces of 16: the last piece is 8 bytes
+	info := &InfoDict{
+		Name:        "dir",
+		PieceLength: 16,
+		Pieces:      make([]byte, 3*20),
+		Files: []File{
+			{Length: 10, Path: []string{"a"}},
+			{Length: 0, Path: []string{"empty"}},
+			{Length: 25, Path: []string{"sub", "b"}},
+			{Length: 5, Path: []string{"c"}},
+		},
+	}
+	layout, err := NewLayout(info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if layout.NumPieces != 3 || !reflect.DeepEqual(layout.Files[2].Path, []string{"dir", "sub", "b"}) || layout.Files[3].Offset != 35 {
+		t.Errorf("layout = %+v", layout)
+	}
+
+	wantSpans := [][]FileSpan{
+		{{File: 0, Offset: 0, Length: 10}, {File: 2, Offset: 0, Length: 6}},
+		{{File: 2, Offset: 6, Length: 16}},
+		{{File: 2, Offset: 22, Length: 3}, {File: 3, Offset: 0, Length: 5}},
+	}
+	wantSizes := []int64{16, 16, 8}
+	for i := range wantSpans {
+		if got := layout.PieceSpans(i); !reflect.DeepEqual(got, wantSpans[i]) {
+			t.Errorf("PieceSpans(%d) = %+v, expected %+v", i, got, wantSpans[i])
+		}
+		if got := layout.PieceSize(i); got != wantSizes[i] {
+			t.Errorf("PieceSize(%d) = %d, expected %d", i, got, wantSizes[i])
+		}
+	}
+	if got := layout.PieceSpans(3); got != nil {
+		t.Errorf("PieceSpans(3) = %+v, expected nil", got)
+	}
+
+	wantRanges := [][2]int{{0, 1}, {0, 0}, {0, 3}, {2, 3}}
+	for i, want := range wantRanges {
+		if first, end := layout.FilePieces(i); first != want[0] || end != want[1] {
+			t.Errorf("FilePieces(%d) = %d, %d, expected %d, %d", i, first, end, want[0], want[1])
+		}
+	}
+}
+
+func TestLayout_SingleFile(t *testing.T) {
+	info := &InfoDict{Name: "file", PieceLength: 16, Pieces: make([]byte, 2*20), Length: 32}
+	layout, err := NewLayout(info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	want := []FileSpan{{File: 0, Offset: 16, Length: 16}}
+	if got := layout.PieceSpans(1); !reflect.DeepEqual(got, want) || layout.PieceSize(1) != 16 {
+		t.Errorf("PieceSpans(1) = %+v, expected %+v", got, want)
+	}
+	if !reflect.DeepEqual(layout.Files[0].Path, []string{"file"}) {
+		t.Errorf("Path = %q", layout.Files[0].Path)
+	}
+}
+
+func TestLayout_V2(t *testing.T) {
+	torrent := newV2Torrent(t, BlockSize, map[string][]byte{
+		"a": testContent(BlockSize+10, 1),
+		"b": testContent(10, 2),
+		"c": nil,
+	})
+	layout, err := NewLayout(&torrent.Info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if layout.NumPieces != torrent.Info.NumPiecesV2() || layout.NumPieces != 3 {
+		t.Errorf("NumPieces = %d, expected 3", layout.NumPieces)
+	}
+	// every file starts a new piece, so pieces never span files
+	want := [][]FileSpan{
+		{{File: 0, Offset: 0, Length: BlockSize}},
+		{{File: 0, Offset: BlockSize, Length: 10}},
+		{{File: 1, Offset: 0, Length: 10}},
+	}
+	for i := range want {
+		if got := layout.PieceSpans(i); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("PieceSpans(%d) = %+v, expected %+v", i, got, want[i])
+		}
+	}
+	if first, end := layout.FilePieces(2); first != 3 || end != 3 {
+		t.Errorf("FilePieces(empty) = %d, %d", first, end)
+	}
+}
+
+func TestNewLayout_Errors(t *testing.T) {
+	tests := []InfoDict{
+		{Name: "zero piece length", Length: 10, Pieces: make([]byte, 20)},
+		{Name: "too few hashes", PieceLength: 4, Length: 10, Pieces: make([]byte, 20)},
+		{Name: "negative length", PieceLength: 4, Files: []File{{Length: -1, Path: []string{"a"}}}},
+	}
+	for _, info := range tests {
+		if _, err := NewLayout(&info); err == nil {
+			t.Errorf("%s: expected an error", info.Name)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package torrent
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Magnet is the content of a magnet link (BEP 9, with BEP 52 hashes and
+// BEP 53 file selection). A link carries a v1 info hash, a v2 one or both.
+type Magnet struct {
+	InfoHash   []byte // 20-byte SHA-1 info hash from xt=urn:btih
+	InfoHashV2 []byte // 32-byte SHA-256 info hash from xt=urn:btmh
+	Name       string // dn
+	Trackers   []string
+	WebSeeds   []string
+	Length     int64       // xl, or 0 when unknown
+	Select     []FileRange // so, the indices of the files to download
+}
+
+// FileRange is an inclusive range of file indices.
+type FileRange struct {
+	First, Last int
+}
+
+// sha256Multihash prefixes a SHA-256 digest in a btmh: the multihash code of
+// SHA-256 followed by the digest length.
+const sha256Multihash = "\x12\x20"
+
+// ParseMagnet parses a magnet link. Parameters may be numbered, as in
+// tr.1=..., and unknown ones are ignored.
+func ParseMagnet(uri string) (*Magnet, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "magnet" {
+		return nil, fmt.Errorf("not a magnet link: scheme %q", u.Scheme)
+	}
+
+	// read the parameters in order, which url.ParseQuery would lose
+	magnet := new(Magnet)
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(param, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, err
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, err
+		}
+		if name, number, found := strings.Cut(key, "."); found {
+			if _, err := strconv.Atoi(number); err == nil {
+				key = name
+			}
+		}
+		if err := magnet.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if magnet.InfoHash == nil && magnet.InfoHashV2 == nil {
+		return nil, errors.New("magnet link has no btih or btmh info hash")
+	}
+	return magnet, nil
+}
+
+func (m *Magnet) set(key, value string) error {
+	var err error
+	switch key {
+	case "xt":
+		err = m.setExactTopic(value)
+	case "dn":
+		m.Name = value
+	case "tr":
+		m.Trackers = append(m.Trackers, value)
+	case "ws":
+		m.WebSeeds = append(m.WebSeeds, value)
+	case "xl":
+		m.Length, err = strconv.ParseInt(value, 10, 64)
+		if err == nil && m.Length < 0 {
+			err = errors.New("negative length")
+		}
+	case "so":
+		m.Select, err = parseFileRanges(value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, value, err)
+	}
+	return nil
+}
+
+func (m *Magnet) setExactTopic(value string) error {
+	switch {
+	case strings.HasPrefix(value, "urn:btih:"):
+		hash, err := decodeInfoHash(strings.TrimPrefix(value, "urn:btih:"))
+		if err != nil {
+			return err
+		}
+		m.InfoHash = hash
+	case strings.HasPrefix(value, "urn:btmh:"):
+		multihash, err := hex.DecodeString(strings.TrimPrefix(value, "urn:btmh:"))
+		if err != nil {
+			return err
+		}
+		if len(multihash) != len(sha256Multihash)+32 || !strings.HasPrefix(string(multihash), sha256Multihash) {
+			return errors.New("not a SHA-256 multihash")
+		}
+		m.InfoHashV2 = multihash[len(sha256Multihash):]
+	}
+	return nil
+}
+
+// decodeInfoHash decodes a v1 info hash written in hex or in base32.
+func decodeInfoHash(text string) ([]byte, error) {
+	switch len(text) {
+	case 40:
+		return hex.DecodeString(text)
+	case 32:
+		return base32.StdEncoding.DecodeString(strings.ToUpper(text))
+	}
+	return nil, fmt.Errorf("info hash of %d characters, expected 40 hex or 32 base32", len(text))
+}
+
+// parseFileRanges parses a BEP 53 selection such as "0,2,4-6".
+func parseFileRanges(text string) ([]FileRange, error) {
+	var ranges []FileRange
+	for _, part := range strings.Split(text, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid file index %q", first)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid file range %q", part)
+			}
+		}
+		ranges = append(ranges, FileRange{First: start, Last: end})
+	}
+	return ranges, nil
+}
+
+// String returns the magnet link, with the v1 info hash in hex.
+func (m *Magnet) String() string {
+	var params []string
+	if m.InfoHash != nil {
+		params = append(params, "xt=urn:btih:"+hex.EncodeToString(m.InfoHash))
+	}
+	if m.InfoHashV2 != nil {
+		params = append(params, "xt=urn:btmh:"+hex.EncodeToString([]byte(sha256Multihash))+hex.EncodeToString(m.InfoHashV2))
+	}
+	if m.Name != "" {
+		params = append(params, "dn="+url.QueryEscape(m.Name))
+	}
+	if m.Length > 0 {
+		params = append(params, "xl="+strconv.FormatInt(m.Length, 10))
+	}
+	for _, tracker := range m.Trackers {
+		params = append(params, "tr="+url.QueryEscape(tracker))
+	}
+	for _, seed := range m.WebSeeds {
+		params = append(params, "ws="+url.QueryEscape(seed))
+	}
+	if len(m.Select) > 0 {
+		ranges := make([]string, len(m.Select))
+		for i, r := range m.Select {
+			ranges[i] = strconv.Itoa(r.First)
+			if r.Last != r.First {
+				ranges[i] += "-" + strconv.Itoa(r.Last)
+			}
+		}
+		params = append(params, "so="+strings.Join(ranges, ","))
+	}
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// Magnet returns a magnet link for the torrent, carrying the info hashes
+// of the versions it has, its name, size, trackers and web seeds.
+func (t *TorrentFile) Magnet() (*Magnet, error) {
+	magnet := &Magnet{Name: t.Info.DisplayName(), Length: t.Info.TotalLength(), WebSeeds: t.URLList}
+	version := t.Info.Version()
+	if version != V2 {
+		hash, _, err := t.InfoHash()
+		if err != nil {
+			return nil, err
+		}
+		magnet.InfoHash = hash
+	}
+	if version != V1 {
+		hash, _, err := t.InfoHashV2()
+		if err != nil {
+			return nil, err
+		}
+		magnet.InfoHashV2 = hash
+	}
+
+	seen := map[string]bool{}
+	for _, tracker := range append([]string{t.Announce}, flatten(t.AnnounceList)...) {
+		if tracker != "" && !seen[tracker] {
+			seen[tracker] = true
+			magnet.Trackers = append(magnet.Trackers, tracker)
+		}
+	}
+	return magnet, nil
+}
+
+func flatten(tiers [][]string) []string {
+	var all []string
+	for _, tier := range tiers {
+		all = append(all, tier...)
+	}
+	return all
+}
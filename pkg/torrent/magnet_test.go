@@ -0,0 +1,115 @@
+package torrent
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestParseMagnet(t *testing.T) {
+	v1, _ := hex.DecodeString("d1aab827cfd1e23dadfe34a24190a0f9c9ffb876")
+	v2, _ := hex.DecodeString("caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105232b28ad099f3a302e")
+	tests := []struct {
+		name    string
+		uri     string
+		want    *Magnet
+		wantErr bool
+	}{
+		{
+			name: "hex info hash",
+			uri:  "magnet:?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876&dn=sub_zip.py&tr=udp%3A%2F%2Ftracker.example.com%3A80&tr=http%3A%2F%2Fother.example.com%2Fannounce",
+			want: &Magnet{
+				InfoHash: v1,
+				Name:     "sub_zip.py",
+				Trackers: []string{"udp://tracker.example.com:80", "http://other.example.com/announce"},
+			},
+		},
+		{
+			name: "base32 info hash",
+			uri:  "magnet:?xt=urn:btih:2gvlqj6p2hrd3lp6gsredefa7he77odw",
+			want: &Magnet{InfoHash: v1},
+		},
+		{
+			name: "hybrid with everything",
+			uri: "magnet:?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876" +
+				"&xt=urn:btmh:1220caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105232b28ad099f3a302e" +
+				"&dn=two+words&xl=1234&tr.1=http%3A%2F%2Fa&tr.2=http%3A%2F%2Fb&ws=http%3A%2F%2Fseed%2Ffile&so=0,2,4-6&x.pe=1.2.3.4:5",
+			want: &Magnet{
+				InfoHash:   v1,
+				InfoHashV2: v2,
+				Name:       "two words",
+				Length:     1234,
+				Trackers:   []string{"http://a", "http://b"},
+				WebSeeds:   []string{"http://seed/file"},
+				Select:     []FileRange{{0, 0}, {2, 2}, {4, 6}},
+			},
+		},
+		{name: "no info hash", uri: "magnet:?dn=name", wantErr: true},
+		{name: "wrong scheme", uri: "http://example.com/?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876", wantErr: true},
+		{name: "short info hash", uri: "magnet:?xt=urn:btih:d1aab8", wantErr: true},
+		{name: "bad hex", uri: "magnet:?xt=urn:btih:z1aab827cfd1e23dadfe34a24190a0f9c9ffb876", wantErr: true},
+		{name: "not sha256 multihash", uri: "magnet:?xt=urn:btmh:1114d1aab827cfd1e23dadfe34a24190a0f9c9ffb876", wantErr: true},
+		{name: "negative length", uri: "magnet:?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876&xl=-1", wantErr: true},
+		{name: "backwards range", uri: "magnet:?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876&so=5-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMagnet(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMagnet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMagnet() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMagnet_StringRoundTrip(t *testing.T) {
+	uri := "magnet:?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876" +
+		"&xt=urn:btmh:1220caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105232b28ad099f3a302e" +
+		"&dn=two+words&xl=1234&tr=http%3A%2F%2Fa&ws=http%3A%2F%2Fseed&so=0%2C4-6"
+	magnet, err := ParseMagnet(uri)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := magnet.String(); got != "magnet:?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876"+
+		"&xt=urn:btmh:1220caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105232b28ad099f3a302e"+
+		"&dn=two+words&xl=1234&tr=http%3A%2F%2Fa&ws=http%3A%2F%2Fseed&so=0,4-6" {
+		t.Errorf("String() = %s", got)
+	}
+	again, err := ParseMagnet(magnet.String())
+	if err != nil || !reflect.DeepEqual(again, magnet) {
+		t.Errorf("ParseMagnet(String()) = %+v, %v, want %+v", again, err, magnet)
+	}
+}
+
+func TestTorrentFile_Magnet(t *testing.T) {
+	torrent, err := NewTorrentFromFile("./testdata/sub_zip.py.torrent")
+	if err != nil {
+		t.Fatalf("Failed to read torrent file: %v", err)
+	}
+	magnet, err := torrent.Magnet()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	want := "magnet:?xt=urn:btih:d1aab827cfd1e23dadfe34a24190a0f9c9ffb876&dn=sub_zip.py&xl=829" +
+		"&tr=udp%3A%2F%2Ftracker.openbittorrent.com%3A80%2Fannounce" +
+		"&tr=udp%3A%2F%2Ftracker.opentrackr.org%3A1337%2Fannounce"
+	if got := magnet.String(); got != want {
+		t.Errorf("Magnet() = %s, want %s", got, want)
+	}
+}
+
+func TestTorrentFile_MagnetV2(t *testing.T) {
+	torrent := newV2Torrent(t, BlockSize, map[string][]byte{"a": testContent(100, 1)})
+	magnet, err := torrent.Magnet()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	hash, _, _ := torrent.InfoHashV2()
+	if magnet.InfoHash != nil || !bytes.Equal(magnet.InfoHashV2, hash) || magnet.Length != 100 {
+		t.Errorf("Magnet() = %+v", magnet)
+	}
+}
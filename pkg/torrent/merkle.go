@@ -0,0 +1,197 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BlockSize is the size of the leaf blocks of the v2 merkle trees.
+const BlockSize = 16 << 10
+
+// nodeHash is a node of a v2 merkle tree.
+type nodeHash [sha256.Size]byte
+
+// FileHashesV2 are the v2 hashes of a file: the root of its merkle tree
+// and, for files longer than a piece, the piece layer to store in the
+// torrent's piece layers.
+type FileHashesV2 struct {
+	Length     int64
+	PiecesRoot []byte
+	PieceLayer []byte
+}
+
+// HashFileV2 reads a whole file from r and computes its v2 hashes. The
+// piece length must be a power of two of at least BlockSize.
+func HashFileV2(r io.Reader, pieceLength int64) (FileHashesV2, error) {
+	if err := checkPieceLengthV2(pieceLength); err != nil {
+		return FileHashesV2{}, err
+	}
+	blocksPerPiece := int(pieceLength / BlockSize)
+
+	var result FileHashesV2
+	var layer []nodeHash
+	leaves := make([]nodeHash, 0, blocksPerPiece)
+	block := make([]byte, BlockSize)
+	for {
+		n, err := io.ReadFull(r, block)
+		if n > 0 {
+			result.Length += int64(n)
+			leaves = append(leaves, sha256.Sum256(block[:n]))
+			if len(leaves) == blocksPerPiece {
+				layer = append(layer, merkleRoot(leaves, blocksPerPiece, nodeHash{}))
+				leaves = leaves[:0]
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return FileHashesV2{}, err
+		}
+	}
+
+	switch {
+	case result.Length == 0:
+		return result, nil
+	case result.Length <= pieceLength:
+		// a file of at most one piece has no piece layer, and its tree is
+		// only as wide as its own blocks need
+		var root nodeHash
+		if len(layer) == 1 {
+			root = layer[0]
+		} else {
+			root = merkleRoot(leaves, nextPowerOfTwo(len(leaves)), nodeHash{})
+		}
+		result.PiecesRoot = root[:]
+		return result, nil
+	}
+
+	if len(leaves) > 0 {
+		layer = append(layer, merkleRoot(leaves, blocksPerPiece, nodeHash{}))
+	}
+	for _, piece := range layer {
+		result.PieceLayer = append(result.PieceLayer, piece[:]...)
+	}
+	root := merkleRoot(layer, nextPowerOfTwo(len(layer)), zeroRoot(blocksPerPiece))
+	result.PiecesRoot = root[:]
+	return result, nil
+}
+
+// VerifyPieceV2 reports whether data is the piece at index of file, which
+// must be one of the torrent's tree files. The last piece of a file may be
+// short.
+func (t *TorrentFile) VerifyPieceV2(file TreeFile, index int, data []byte) (bool, error) {
+	pieceLength := t.Info.PieceLength
+	if err := checkPieceLengthV2(pieceLength); err != nil {
+		return false, err
+	}
+	numPieces := int((file.Length + pieceLength - 1) / pieceLength)
+	if index < 0 || index >= numPieces {
+		return false, fmt.Errorf("piece %d out of range for a file of %d pieces", index, numPieces)
+	}
+
+	leaves := make([]nodeHash, 0, (len(data)+BlockSize-1)/BlockSize)
+	for start := 0; start < len(data); start += BlockSize {
+		end := start + BlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		leaves = append(leaves, sha256.Sum256(data[start:end]))
+	}
+
+	if file.Length <= pieceLength {
+		root := merkleRoot(leaves, nextPowerOfTwo(len(leaves)), nodeHash{})
+		return bytes.Equal(root[:], file.PiecesRoot), nil
+	}
+	layer, ok := t.PieceLayers[string(file.PiecesRoot)]
+	if !ok || len(layer) != numPieces*sha256.Size {
+		return false, fmt.Errorf("no valid piece layer for pieces root %x", file.PiecesRoot)
+	}
+	root := merkleRoot(leaves, int(pieceLength/BlockSize), nodeHash{})
+	return bytes.Equal(root[:], layer[index*sha256.Size:(index+1)*sha256.Size]), nil
+}
+
+// CheckPieceLayers checks that every file longer than a piece has a piece
+// layer of the right size hashing up to its pieces root.
+func (t *TorrentFile) CheckPieceLayers() error {
+	pieceLength := t.Info.PieceLength
+	if err := checkPieceLengthV2(pieceLength); err != nil {
+		return err
+	}
+	for _, file := range t.Info.FileTree.Files() {
+		if file.Length > 0 && len(file.PiecesRoot) != sha256.Size {
+			return fmt.Errorf("file %q has a pieces root of %d bytes", file.Path, len(file.PiecesRoot))
+		}
+		if file.Length <= pieceLength {
+			continue
+		}
+		layer, ok := t.PieceLayers[string(file.PiecesRoot)]
+		if !ok {
+			return fmt.Errorf("file %q has no piece layer", file.Path)
+		}
+		numPieces := int((file.Length + pieceLength - 1) / pieceLength)
+		if len(layer) != numPieces*sha256.Size {
+			return fmt.Errorf("piece layer of file %q has %d bytes, expected %d", file.Path, len(layer), numPieces*sha256.Size)
+		}
+		pieces := make([]nodeHash, numPieces)
+		for i := range pieces {
+			copy(pieces[i][:], layer[i*sha256.Size:])
+		}
+		root := merkleRoot(pieces, nextPowerOfTwo(numPieces), zeroRoot(int(pieceLength/BlockSize)))
+		if !bytes.Equal(root[:], file.PiecesRoot) {
+			return fmt.Errorf("piece layer of file %q does not match its pieces root", file.Path)
+		}
+	}
+	return nil
+}
+
+func checkPieceLengthV2(pieceLength int64) error {
+	if pieceLength < BlockSize || pieceLength&(pieceLength-1) != 0 {
+		return errors.New("v2 piece length must be a power of two of at least 16 KiB")
+	}
+	return nil
+}
+
+// merkleRoot hashes a layer of a merkle tree up to its root, treating the
+// layer as width hashes long with the missing ones equal to pad. Width must
+// be a power of two. The layer is overwritten.
+func merkleRoot(layer []nodeHash, width int, pad nodeHash) nodeHash {
+	for ; width > 1; width /= 2 {
+		for i := 0; i < len(layer); i += 2 {
+			right := pad
+			if i+1 < len(layer) {
+				right = layer[i+1]
+			}
+			layer[i/2] = hashPair(layer[i], right)
+		}
+		layer = layer[:(len(layer)+1)/2]
+		pad = hashPair(pad, pad)
+	}
+	if len(layer) == 0 {
+		return pad
+	}
+	return layer[0]
+}
+
+// zeroRoot returns the root of a tree of width zero leaves.
+func zeroRoot(width int) nodeHash {
+	return merkleRoot(nil, width, nodeHash{})
+}
+
+func hashPair(left, right nodeHash) nodeHash {
+	var pair [2 * sha256.Size]byte
+	copy(pair[:], left[:])
+	copy(pair[sha256.Size:], right[:])
+	return sha256.Sum256(pair[:])
+}
+
+func nextPowerOfTwo(n int) int {
+	width := 1
+	for width < n {
+		width *= 2
+	}
+	return width
+}
@@ -3,6 +3,7 @@ package torrent
 import (
 	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"io"
 	"os"
@@ -10,25 +11,88 @@ import (
 )
 
 type TorrentFile struct {
-	Announce     string     `bencode:"announce"`
-	AnnounceList [][]string `bencode:"announce-list"`
-	CreationDate int64      `bencode:"creation date"`
-	Comment      string     `bencode:"comment"`
-	CreatedBy    string     `bencode:"created by"`
-	Info         InfoDict   `bencode:"info"`
+	Announce     string     `bencode:"announce,omitempty"`
+	AnnounceList [][]string `bencode:"announce-list,omitempty"`
+	CreationDate int64      `bencode:"creation date,omitempty"`
+	Comment      string     `bencode:"comment,omitempty"`
+	CreatedBy    string     `bencode:"created by,omitempty"`
+	Info         InfoDict   `bencode:"info,required"`
+
+	URLList   URLList  `bencode:"url-list,omitempty"`  // BEP 19 web seeds
+	HTTPSeeds []string `bencode:"httpseeds,omitempty"` // BEP 17 HTTP seeds
+	Nodes     []Node   `bencode:"nodes,omitempty"`     // BEP 5 DHT bootstrap nodes
+
+	// PieceLayers maps the pieces root of each v2 file longer than a piece
+	// to the concatenated SHA-256 hashes of its pieces.
+	PieceLayers map[string][]byte `bencode:"piece layers,omitempty"`
+
+	// Extra keeps the keys not modelled above, such as client or tracker
+	// extensions, so they survive a Marshal.
+	Extra map[string]bencoder.RawMessage `bencode:",extra"`
+
+	// rawInfo holds the info dict exactly as it appeared in the loaded
+	// metainfo, since the info hash has to be computed over those bytes.
+	rawInfo bencoder.RawMessage
 }
 
+// InfoDict holds either Length for a single-file torrent or Files for a
+// multi-file one; the other is left out when marshalling. A v2 torrent
+// describes its content in FileTree instead and has no Pieces, and a hybrid
+// one has both.
 type InfoDict struct {
-	PieceLength int64  `bencode:"piece length"`
-	Pieces      []byte `bencode:"pieces"`
-	Name        string `bencode:"name"`
-	Length      int64  `bencode:"length"`
-	Files       []File `bencode:"files"`
+	PieceLength int64    `bencode:"piece length"`
+	Pieces      []byte   `bencode:"pieces,omitempty"`
+	Name        string   `bencode:"name"`
+	Length      int64    `bencode:"length,omitempty"`
+	Files       []File   `bencode:"files,omitempty"`
+	MetaVersion int64    `bencode:"meta version,omitempty"`
+	FileTree    FileTree `bencode:"file tree,omitempty"`
+
+	// Private is 1 in BEP 27 private torrents, whose peers may only come
+	// from their trackers. It is a pointer to an integer so that whatever
+	// value a torrent has, an explicit 0 included, is kept and the info
+	// hash does not change.
+	Private     *int64   `bencode:"private,omitempty"`
+	Source      string   `bencode:"source,omitempty"`
+	NameUTF8    string   `bencode:"name.utf-8,omitempty"`
+	MD5Sum      string   `bencode:"md5sum,omitempty"`
+	Collections []string `bencode:"collections,omitempty"` // BEP 38
+	Similar     [][]byte `bencode:"similar,omitempty"`     // BEP 38 info hashes
+
+	// Extra keeps the info keys not modelled above. They are part of the
+	// info hash, so dropping them would change it.
+	Extra map[string]bencoder.RawMessage `bencode:",extra"`
 }
 
 type File struct {
-	Length int64    `bencode:"length"`
-	Path   []string `bencode:"path"`
+	Length   int64    `bencode:"length"`
+	Path     []string `bencode:"path"`
+	PathUTF8 []string `bencode:"path.utf-8,omitempty"`
+	MD5Sum   string   `bencode:"md5sum,omitempty"`
+
+	// Attr holds the BEP 47 attribute letters of the file, see HasAttr,
+	// and SymlinkPath the target of a symlink relative to the torrent.
+	Attr        string   `bencode:"attr,omitempty"`
+	SymlinkPath []string `bencode:"symlink path,omitempty"`
+}
+
+// TotalLength returns the size of the content: the sum of its files, not
+// counting padding, or the length of its single file.
+func (info *InfoDict) TotalLength() int64 {
+	if info.Version() == V2 {
+		var total int64
+		for _, file := range info.FileTree.Files() {
+			total += file.Length
+		}
+		return total
+	}
+	total := info.Length
+	for _, file := range info.Files {
+		if !file.IsPadding() {
+			total += file.Length
+		}
+	}
+	return total
 }
 
 func GeneratePieces(data []byte, pieceLength int) string {
@@ -45,14 +109,32 @@ func GeneratePieces(data []byte, pieceLength int) string {
 }
 
 func NewTorrentFromBencode(data []byte) (*TorrentFile, error) {
-	var torrentFile TorrentFile
-	err := bencoder.NewSimpleBencoder().Unmarshal(data, &torrentFile)
-	if err != nil {
+	// the info field shadows the one of TorrentFile, so the info dict is
+	// decoded and its bytes kept in the same pass over data
+	var decoded struct {
+		TorrentFile
+		Info rawInfoDict `bencode:"info,required"`
+	}
+	if err := bencoder.NewSimpleBencoder().Unmarshal(data, &decoded); err != nil {
 		return nil, err
 	}
+	torrentFile := decoded.TorrentFile
+	torrentFile.Info = decoded.Info.InfoDict
+	torrentFile.rawInfo = decoded.Info.raw
 	return &torrentFile, nil
 }
 
+// rawInfoDict decodes an info dict and keeps the bytes it was decoded from.
+type rawInfoDict struct {
+	InfoDict
+	raw bencoder.RawMessage
+}
+
+func (r *rawInfoDict) UnmarshalBencode(data []byte) error {
+	r.raw = data
+	return bencoder.NewDecoder(bytes.NewReader(data)).Decode(&r.InfoDict)
+}
+
 func NewTorrentFromFile(filePath string) (*TorrentFile, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -62,18 +144,50 @@ func NewTorrentFromFile(filePath string) (*TorrentFile, error) {
 }
 
 func NewTorrentFromReader(r io.Reader) (*TorrentFile, error) {
-	data, err := io.ReadAll(r)
-	if err != nil {
+	// read exactly one value, leaving anything after it in r
+	var data bencoder.RawMessage
+	if err := bencoder.NewDecoder(r).Decode(&data); err != nil {
 		return nil, err
 	}
 	return NewTorrentFromBencode(data)
 }
 
+// InfoHash returns the SHA-1 hash of the info dict, the v1 info hash.
+// Torrents loaded from bencode hash the info dict bytes they were read
+// from; torrents built in code hash the marshalled Info.
 func (t *TorrentFile) InfoHash() ([]byte, string, error) {
-	benc, err := bencoder.NewSimpleBencoder().Marshal(t.Info)
+	benc, err := t.infoBytes()
 	if err != nil {
 		return nil, "", err
 	}
 	hash := sha1.Sum(benc)
 	return hash[:], hex.EncodeToString(hash[:]), nil
 }
+
+// InfoHashV2 returns the SHA-256 hash of the info dict, the v2 info hash.
+func (t *TorrentFile) InfoHashV2() ([]byte, string, error) {
+	benc, err := t.infoBytes()
+	if err != nil {
+		return nil, "", err
+	}
+	hash := sha256.Sum256(benc)
+	return hash[:], hex.EncodeToString(hash[:]), nil
+}
+
+// TruncatedInfoHashV2 returns the v2 info hash cut to 20 bytes, the form
+// used where only a SHA-1 sized hash fits, such as trackers and the peer
+// handshake.
+func (t *TorrentFile) TruncatedInfoHashV2() ([]byte, string, error) {
+	hash, _, err := t.InfoHashV2()
+	if err != nil {
+		return nil, "", err
+	}
+	return hash[:sha1.Size], hex.EncodeToString(hash[:sha1.Size]), nil
+}
+
+func (t *TorrentFile) infoBytes() ([]byte, error) {
+	if t.rawInfo != nil {
+		return t.rawInfo, nil
+	}
+	return bencoder.NewSimpleBencoder().Marshal(t.Info)
+}
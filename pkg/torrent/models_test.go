@@ -3,10 +3,13 @@ package torrent
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"torrent/pkg/bencoder"
 )
 
 func TestNewTorrentFromBencode(t *testing.T) {
@@ -42,6 +45,7 @@ func TestNewTorrentFromBencode(t *testing.T) {
 						},
 					},
 				},
+				rawInfo: []byte("d12:piece lengthi1000e6:pieces5:\x01\x02\x03\x04\x054:name4:Test6:lengthi5e5:filesld6:lengthi1e4:pathl6:/home/eeee"),
 			},
 			wantErr: false,
 		},
@@ -184,3 +188,80 @@ func TestInfoHash(t *testing.T) {
 		t.Errorf("decoded hex hash does not match raw hash")
 	}
 }
+
+func TestMarshalSingleFileInfo(t *testing.T) {
+	torrent, err := NewTorrentFromFile("./testdata/sub_zip.py.torrent")
+	if err != nil {
+		t.Fatalf("Failed to read torrent file: %v", err)
+	}
+
+	info, err := bencoder.NewSimpleBencoder().Marshal(torrent.Info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// a single-file info dict carries no files list and re-encodes exactly
+	if !bytes.Equal(info, torrent.rawInfo) {
+		t.Errorf("Marshal mismatch. Got %q, expected %q", info, torrent.rawInfo)
+	}
+}
+
+func TestNewTorrentFromBencodeMissingInfo(t *testing.T) {
+	_, err := NewTorrentFromBencode([]byte("d8:announce15:http://test.come"))
+	if err == nil {
+		t.Errorf("expected error for metainfo without info dict")
+	}
+}
+
+func TestNewTorrentFromBencodeInfoErrors(t *testing.T) {
+	// errors in the info dict are located within the whole document
+	_, err := NewTorrentFromBencode([]byte("d4:infod4:name1:a12:piece length3:bigee"))
+	var typeErr *bencoder.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Path != "info.piece length" || typeErr.Offset != 32 {
+		t.Errorf("NewTorrentFromBencode() error = %v, expected one at info.piece length, offset 32", err)
+	}
+}
+
+func TestInfoHashUsesRawInfo(t *testing.T) {
+	torrent, err := NewTorrentFromFile("./testdata/sub_zip.py.torrent")
+	if err != nil {
+		t.Fatalf("Failed to read torrent file: %v", err)
+	}
+
+	// the hash of the info dict bytes as they appear in the file
+	_, hexHash, err := torrent.InfoHash()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if expected := "d1aab827cfd1e23dadfe34a24190a0f9c9ffb876"; hexHash != expected {
+		t.Errorf("InfoHash mismatch. Got %s, expected %s", hexHash, expected)
+	}
+}
+
+func TestMarshalKeepsUnknownKeys(t *testing.T) {
+	data := []byte("d8:announce3:url4:infod6:lengthi5e4:name4:demo12:piece lengthi16e6:pieces20:" + strings.Repeat("\x01", 20) + "7:privatei1e6:source3:abc6:x-infoi2ee9:x-trackeri1ee")
+	torrent, err := NewTorrentFromBencode(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if string(torrent.Extra["x-tracker"]) != "i1e" || string(torrent.Info.Extra["x-info"]) != "i2e" {
+		t.Errorf("unknown keys not captured: %q, %q", torrent.Extra, torrent.Info.Extra)
+	}
+	if !torrent.Info.IsPrivate() || torrent.Info.Source != "abc" || len(torrent.Info.Extra) != 1 {
+		t.Errorf("known keys not decoded into fields: %+v", torrent.Info)
+	}
+
+	encoded, err := bencoder.NewSimpleBencoder().Marshal(torrent)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("Marshal mismatch. Got %q, expected %q", encoded, data)
+	}
+
+	// a torrent built in code hashes the same info dict, extra keys included
+	rebuilt := TorrentFile{Info: torrent.Info}
+	_, want, _ := torrent.InfoHash()
+	if _, got, _ := rebuilt.InfoHash(); got != want {
+		t.Errorf("InfoHash mismatch. Got %s, expected %s", got, want)
+	}
+}
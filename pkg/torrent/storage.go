@@ -0,0 +1,142 @@
+package torrent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Storage keeps the content of a torrent in files under a directory, placed
+// as its Layout says and named with SafeJoin. Padding files are never
+// created: they read as zeros, so pieces still hash correctly, and the
+// zeros written to them are dropped.
+type Storage struct {
+	Dir    string
+	Layout *Layout
+}
+
+// NewStorage returns the storage for the content of info under dir. It
+// creates every file that is not padding, so that files no piece covers,
+// those of zero length, exist too. Files already there keep their data, up
+// to their length, so a download can be resumed.
+func NewStorage(dir string, info *InfoDict) (*Storage, error) {
+	layout, err := NewLayout(info)
+	if err != nil {
+		return nil, err
+	}
+	s := &Storage{Dir: dir, Layout: layout}
+	for _, file := range layout.Files {
+		if file.Padding {
+			continue
+		}
+		if err := s.create(file); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// WritePiece writes the data of the piece at index to the files it covers,
+// creating them as needed.
+func (s *Storage) WritePiece(index int, data []byte) error {
+	if index < 0 || index >= s.Layout.NumPieces {
+		return fmt.Errorf("piece %d out of range", index)
+	}
+	if size := s.Layout.PieceSize(index); int64(len(data)) != size {
+		return fmt.Errorf("piece %d has %d bytes, not %d", index, len(data), size)
+	}
+	var done int64
+	for _, span := range s.Layout.PieceSpans(index) {
+		part := data[done : done+span.Length]
+		done += span.Length
+		file := s.Layout.Files[span.File]
+		if file.Padding {
+			continue
+		}
+		if err := s.writeAt(file, part, span.Offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPiece reads the piece at index from the files it covers.
+func (s *Storage) ReadPiece(index int) ([]byte, error) {
+	if index < 0 || index >= s.Layout.NumPieces {
+		return nil, fmt.Errorf("piece %d out of range", index)
+	}
+	data := make([]byte, s.Layout.PieceSize(index))
+	var done int64
+	for _, span := range s.Layout.PieceSpans(index) {
+		part := data[done : done+span.Length]
+		done += span.Length
+		file := s.Layout.Files[span.File]
+		if file.Padding {
+			continue
+		}
+		if err := s.readAt(file, part, span.Offset); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// create creates file if it does not exist, and cuts it to its length if
+// it is longer.
+func (s *Storage) create(file LayoutFile) error {
+	path, err := SafeJoin(s.Dir, file.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil && info.Size() > file.Length {
+		err = f.Truncate(file.Length)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Storage) writeAt(file LayoutFile, data []byte, offset int64) error {
+	path, err := SafeJoin(s.Dir, file.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, offset); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Storage) readAt(file LayoutFile, data []byte, offset int64) error {
+	path, err := SafeJoin(s.Dir, file.Path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.ReadAt(data, offset); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
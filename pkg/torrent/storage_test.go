@@ -0,0 +1,115 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorage_SkipsPadding(t *testing.T) {
+	a, b := testContent(20, 1), testContent(10, 2)
+	content := bytes.Join([][]byte{a, make([]byte, 12), b}, nil)
+	info := &InfoDict{
+		Name:        "dir",
+		PieceLength: 16,
+		Pieces:      []byte(GeneratePieces(content, 16)),
+		Files: []File{
+			{Length: 20, Path: []string{"a"}},
+			paddingFile(12),
+			{Length: 10, Path: []string{"b"}},
+		},
+	}
+	dir := t.TempDir()
+	storage, err := NewStorage(dir, info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !storage.Layout.Files[1].Padding || storage.Layout.Files[2].Offset != 32 {
+		t.Fatalf("layout = %+v", storage.Layout)
+	}
+
+	for i := 0; i < storage.Layout.NumPieces; i++ {
+		start := int64(i) * 16
+		end := start + storage.Layout.PieceSize(i)
+		if err := storage.WritePiece(i, content[start:end]); err != nil {
+			t.Fatalf("WritePiece(%d) error = %v", i, err)
+		}
+	}
+	for name, want := range map[string][]byte{"a": a, "b": b} {
+		got, err := os.ReadFile(filepath.Join(dir, "dir", name))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("file %s = %x, %v, expected %x", name, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dir", ".pad")); !os.IsNotExist(err) {
+		t.Errorf("padding was written to disk: %v", err)
+	}
+
+	// the padding reads back as zeros, so every piece matches its hash
+	for i := 0; i < storage.Layout.NumPieces; i++ {
+		piece, err := storage.ReadPiece(i)
+		if err != nil {
+			t.Fatalf("ReadPiece(%d) error = %v", i, err)
+		}
+		if sum := sha1.Sum(piece); !bytes.Equal(sum[:], info.Pieces[i*20:i*20+20]) {
+			t.Errorf("piece %d does not match its hash", i)
+		}
+	}
+}
+
+func TestStorage_CreatesFiles(t *testing.T) {
+	info := &InfoDict{
+		Name:        "dir",
+		PieceLength: 16,
+		Pieces:      make([]byte, 20),
+		Files: []File{
+			{Length: 0, Path: []string{"empty", "a"}},
+			{Length: 10, Path: []string{"b"}},
+			paddingFile(6),
+		},
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// a longer file left from before is cut to its length
+	if err := os.WriteFile(filepath.Join(dir, "dir", "b"), testContent(30, 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStorage(dir, info); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for name, want := range map[string]int64{"empty/a": 0, "b": 10} {
+		stat, err := os.Stat(filepath.Join(dir, "dir", name))
+		if err != nil || stat.Size() != want {
+			t.Errorf("file %s: %v, %v, expected size %d", name, stat, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dir", ".pad")); !os.IsNotExist(err) {
+		t.Errorf("padding was created on disk: %v", err)
+	}
+}
+
+func TestStorage_Errors(t *testing.T) {
+	info := &InfoDict{Name: "file", PieceLength: 16, Pieces: make([]byte, 2*20), Length: 20}
+	storage, err := NewStorage(t.TempDir(), info)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := storage.WritePiece(0, make([]byte, 10)); err == nil {
+		t.Errorf("expected an error for a short piece")
+	}
+	if _, err := storage.ReadPiece(0); err == nil {
+		t.Errorf("expected an error reading a file not yet written")
+	}
+	for _, index := range []int{-1, 2, 99} {
+		if err := storage.WritePiece(index, nil); err == nil {
+			t.Errorf("WritePiece(%d) succeeded, expected an error for a piece out of range", index)
+		}
+		if _, err := storage.ReadPiece(index); err == nil {
+			t.Errorf("ReadPiece(%d) succeeded, expected an error for a piece out of range", index)
+		}
+	}
+}
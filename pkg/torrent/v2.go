@@ -0,0 +1,139 @@
+package torrent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"torrent/pkg/bencoder"
+)
+
+// Version is the metainfo format of a torrent.
+type Version int
+
+const (
+	V1     Version = iota + 1 // BEP 3: pieces and length or files
+	V2                        // BEP 52: meta version 2 and a file tree
+	Hybrid                    // both, describing the same content
+)
+
+func (v Version) String() string {
+	switch v {
+	case V1:
+		return "v1"
+	case V2:
+		return "v2"
+	case Hybrid:
+		return "hybrid"
+	}
+	return "unknown"
+}
+
+// FileTree is the v2 description of the content: each name maps to a file
+// or to a directory holding a further tree.
+type FileTree map[string]FileTreeNode
+
+// FileTreeNode is an entry of a FileTree. A file has File set and a
+// directory has Dir set. Files are encoded as a dict with the single key "".
+type FileTreeNode struct {
+	File *TreeFileInfo
+	Dir  FileTree
+}
+
+// TreeFileInfo describes a file in a v2 file tree. Empty files have no
+// pieces root.
+type TreeFileInfo struct {
+	Length     int64  `bencode:"length,required"`
+	PiecesRoot []byte `bencode:"pieces root,omitempty"`
+}
+
+// TreeFile is a file of a v2 file tree along with its path.
+type TreeFile struct {
+	Path []string
+	TreeFileInfo
+}
+
+func (n FileTreeNode) MarshalBencode() ([]byte, error) {
+	if n.File != nil {
+		return bencoder.Marshal(map[string]*TreeFileInfo{"": n.File})
+	}
+	if n.Dir == nil {
+		return []byte("de"), nil
+	}
+	return bencoder.Marshal(n.Dir)
+}
+
+func (n *FileTreeNode) UnmarshalBencode(data []byte) error {
+	var entries map[string]bencoder.RawMessage
+	if err := bencoder.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return err
+	}
+	*n = FileTreeNode{}
+	if raw, ok := entries[""]; ok {
+		if len(entries) != 1 {
+			return errors.New("file tree entry holds both a file and other entries")
+		}
+		n.File = new(TreeFileInfo)
+		return bencoder.NewDecoder(bytes.NewReader(raw)).Decode(n.File)
+	}
+	n.Dir = make(FileTree, len(entries))
+	for name, raw := range entries {
+		var child FileTreeNode
+		if err := child.UnmarshalBencode(raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		n.Dir[name] = child
+	}
+	return nil
+}
+
+// Files returns the files of the tree in the order their keys sort, which
+// is the order their pieces follow each other in.
+func (tree FileTree) Files() []TreeFile {
+	var files []TreeFile
+	tree.collect(nil, &files)
+	return files
+}
+
+func (tree FileTree) collect(path []string, files *[]TreeFile) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		node := tree[name]
+		nodePath := append(path[:len(path):len(path)], name)
+		if node.File != nil {
+			*files = append(*files, TreeFile{Path: nodePath, TreeFileInfo: *node.File})
+			continue
+		}
+		node.Dir.collect(nodePath, files)
+	}
+}
+
+// Version reports which formats the info dict describes its content in.
+// Only meta version 2 dicts carry a file tree; one that also has the v1
+// keys is a hybrid.
+func (info *InfoDict) Version() Version {
+	switch {
+	case info.MetaVersion != 2:
+		return V1
+	case len(info.Pieces) > 0 || info.Length > 0 || len(info.Files) > 0:
+		return Hybrid
+	}
+	return V2
+}
+
+// NumPiecesV2 returns the number of v2 pieces. Every file starts a new
+// piece, so the count is summed per file.
+func (info *InfoDict) NumPiecesV2() int {
+	if info.PieceLength <= 0 {
+		return 0
+	}
+	count := 0
+	for _, file := range info.FileTree.Files() {
+		count += int((file.Length + info.PieceLength - 1) / info.PieceLength)
+	}
+	return count
+}
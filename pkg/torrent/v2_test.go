@@ -0,0 +1,246 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+	"torrent/pkg/bencoder"
+)
+
+func testContent(length int, seed byte) []byte {
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = byte(i*7) + seed
+	}
+	return data
+}
+
+func sum(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func TestHashFileV2_SinglePiece(t *testing.T) {
+	data := testContent(2*BlockSize+100, 1)
+	hashes, err := HashFileV2(bytes.NewReader(data), 4*BlockSize)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// three leaves padded to four with a zero hash
+	zero := make([]byte, sha256.Size)
+	want := sum(
+		sum(sum(data[:BlockSize]), sum(data[BlockSize:2*BlockSize])),
+		sum(sum(data[2*BlockSize:]), zero),
+	)
+	if !bytes.Equal(hashes.PiecesRoot, want) {
+		t.Errorf("PiecesRoot = %x, expected %x", hashes.PiecesRoot, want)
+	}
+	if hashes.PieceLayer != nil || hashes.Length != int64(len(data)) {
+		t.Errorf("got layer %x and length %d for a single-piece file", hashes.PieceLayer, hashes.Length)
+	}
+}
+
+func TestHashFileV2_PieceLayer(t *testing.T) {
+	// pieces of two blocks: the last one is a single short block
+	data := testContent(4*BlockSize+10, 2)
+	hashes, err := HashFileV2(bytes.NewReader(data), 2*BlockSize)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	zero := make([]byte, sha256.Size)
+	pieces := [][]byte{
+		sum(sum(data[:BlockSize]), sum(data[BlockSize:2*BlockSize])),
+		sum(sum(data[2*BlockSize:3*BlockSize]), sum(data[3*BlockSize:4*BlockSize])),
+		sum(sum(data[4*BlockSize:]), zero),
+	}
+	if want := bytes.Join(pieces, nil); !bytes.Equal(hashes.PieceLayer, want) {
+		t.Errorf("PieceLayer = %x, expected %x", hashes.PieceLayer, want)
+	}
+	// the fourth piece pads with the root of a piece of zero blocks
+	zeroPiece := sum(zero, zero)
+	if want := sum(sum(pieces[0], pieces[1]), sum(pieces[2], zeroPiece)); !bytes.Equal(hashes.PiecesRoot, want) {
+		t.Errorf("PiecesRoot = %x, expected %x", hashes.PiecesRoot, want)
+	}
+}
+
+func TestHashFileV2_Empty(t *testing.T) {
+	hashes, err := HashFileV2(bytes.NewReader(nil), BlockSize)
+	if err != nil || hashes.PiecesRoot != nil || hashes.Length != 0 {
+		t.Errorf("HashFileV2(empty) = %+v, %v", hashes, err)
+	}
+}
+
+func TestHashFileV2_InvalidPieceLength(t *testing.T) {
+	for _, pieceLength := range []int64{0, BlockSize / 2, 3 * BlockSize} {
+		if _, err := HashFileV2(bytes.NewReader(nil), pieceLength); err == nil {
+			t.Errorf("expected an error for piece length %d", pieceLength)
+		}
+	}
+}
+
+// newV2Torrent builds a v2 torrent of the given files, keyed by slash-free
+// paths below a directory.
+func newV2Torrent(t *testing.T, pieceLength int64, files map[string][]byte) *TorrentFile {
+	t.Helper()
+	torrent := &TorrentFile{
+		Announce: "http://tracker.example.com/announce",
+		Info: InfoDict{
+			PieceLength: pieceLength,
+			Name:        "demo",
+			MetaVersion: 2,
+			FileTree:    FileTree{"dir": {Dir: FileTree{}}},
+		},
+		PieceLayers: map[string][]byte{},
+	}
+	for name, data := range files {
+		hashes, err := HashFileV2(bytes.NewReader(data), pieceLength)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		torrent.Info.FileTree["dir"].Dir[name] = FileTreeNode{File: &TreeFileInfo{Length: hashes.Length, PiecesRoot: hashes.PiecesRoot}}
+		if hashes.PieceLayer != nil {
+			torrent.PieceLayers[string(hashes.PiecesRoot)] = hashes.PieceLayer
+		}
+	}
+	return torrent
+}
+
+func TestV2_RoundTrip(t *testing.T) {
+	files := map[string][]byte{
+		"a.bin":   testContent(3*BlockSize+1, 3),
+		"b.txt":   testContent(100, 4),
+		"empty":   nil,
+		"c.piece": testContent(2*BlockSize, 5),
+	}
+	built := newV2Torrent(t, 2*BlockSize, files)
+	data, err := bencoder.NewSimpleBencoder().Marshal(built)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	torrent, err := NewTorrentFromBencode(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(torrent.Info.FileTree, built.Info.FileTree) || !reflect.DeepEqual(torrent.PieceLayers, built.PieceLayers) {
+		t.Errorf("round trip mismatch: got %+v, expected %+v", torrent.Info.FileTree, built.Info.FileTree)
+	}
+	if torrent.Info.Version() != V2 {
+		t.Errorf("Version() = %s, expected v2", torrent.Info.Version())
+	}
+	if err := torrent.CheckPieceLayers(); err != nil {
+		t.Errorf("CheckPieceLayers() error = %v", err)
+	}
+	if got := torrent.Info.NumPiecesV2(); got != 4 {
+		t.Errorf("NumPiecesV2() = %d, expected 4", got)
+	}
+
+	var paths [][]string
+	for _, file := range torrent.Info.FileTree.Files() {
+		paths = append(paths, file.Path)
+	}
+	want := [][]string{{"dir", "a.bin"}, {"dir", "b.txt"}, {"dir", "c.piece"}, {"dir", "empty"}}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Files() paths = %q, expected %q", paths, want)
+	}
+
+	full, _, _ := torrent.InfoHashV2()
+	if want := sum(torrent.rawInfo); !bytes.Equal(full, want) {
+		t.Errorf("InfoHashV2() = %x, expected %x", full, want)
+	}
+	truncated, hexHash, _ := torrent.TruncatedInfoHashV2()
+	if !bytes.Equal(truncated, full[:20]) || len(hexHash) != 40 {
+		t.Errorf("TruncatedInfoHashV2() = %x, expected %x", truncated, full[:20])
+	}
+	if rebuilt, _, _ := built.InfoHashV2(); !bytes.Equal(rebuilt, full) {
+		t.Errorf("InfoHashV2() of the built torrent = %x, expected %x", rebuilt, full)
+	}
+}
+
+func TestVerifyPieceV2(t *testing.T) {
+	data := testContent(3*BlockSize+1, 6)
+	small := testContent(100, 7)
+	torrent := newV2Torrent(t, 2*BlockSize, map[string][]byte{"a": data, "b": small})
+	files := torrent.Info.FileTree.Files()
+
+	pieces := [][]byte{data[:2*BlockSize], data[2*BlockSize:]}
+	for i, piece := range pieces {
+		if ok, err := torrent.VerifyPieceV2(files[0], i, piece); !ok || err != nil {
+			t.Errorf("VerifyPieceV2(piece %d) = %v, %v", i, ok, err)
+		}
+	}
+	if ok, _ := torrent.VerifyPieceV2(files[0], 0, pieces[1]); ok {
+		t.Errorf("VerifyPieceV2 accepted the wrong piece")
+	}
+	if ok, err := torrent.VerifyPieceV2(files[1], 0, small); !ok || err != nil {
+		t.Errorf("VerifyPieceV2(small file) = %v, %v", ok, err)
+	}
+	if _, err := torrent.VerifyPieceV2(files[0], 2, nil); err == nil {
+		t.Errorf("expected an error for a piece out of range")
+	}
+}
+
+func TestCheckPieceLayers_Errors(t *testing.T) {
+	data := testContent(3*BlockSize, 8)
+	tests := []struct {
+		name   string
+		modify func(torrent *TorrentFile)
+	}{
+		{"missing layer", func(torrent *TorrentFile) {
+			torrent.PieceLayers = nil
+		}},
+		{"short layer", func(torrent *TorrentFile) {
+			for root, layer := range torrent.PieceLayers {
+				torrent.PieceLayers[root] = layer[:sha256.Size]
+			}
+		}},
+		{"wrong layer", func(torrent *TorrentFile) {
+			for _, layer := range torrent.PieceLayers {
+				layer[0] ^= 1
+			}
+		}},
+		{"bad piece length", func(torrent *TorrentFile) {
+			torrent.Info.PieceLength = 3 * BlockSize
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			torrent := newV2Torrent(t, 2*BlockSize, map[string][]byte{"a": data})
+			test.modify(torrent)
+			if err := torrent.CheckPieceLayers(); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		info InfoDict
+		want Version
+	}{
+		{InfoDict{Pieces: make([]byte, 20), Length: 1}, V1},
+		{InfoDict{MetaVersion: 2, FileTree: FileTree{"a": {File: &TreeFileInfo{Length: 1}}}}, V2},
+		{InfoDict{MetaVersion: 2, Pieces: make([]byte, 20), Length: 1, FileTree: FileTree{"a": {File: &TreeFileInfo{Length: 1}}}}, Hybrid},
+	}
+	for _, test := range tests {
+		if got := test.info.Version(); got != test.want {
+			t.Errorf("Version() = %s, expected %s", got, test.want)
+		}
+	}
+}
+
+func TestFileTreeNode_Invalid(t *testing.T) {
+	for _, data := range []string{"d0:d6:lengthi1ee1:ad0:d6:lengthi1eeee", "i1e", "d1:ai1ee"} {
+		var node FileTreeNode
+		if err := node.UnmarshalBencode([]byte(data)); err == nil {
+			t.Errorf("UnmarshalBencode(%q) succeeded", data)
+		}
+	}
+}
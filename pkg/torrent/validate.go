@@ -0,0 +1,399 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+	"torrent/pkg/bencoder"
+	"unicode/utf8"
+)
+
+// Severity says whether a Problem makes a torrent unusable.
+type Severity int
+
+const (
+	SeverityError   Severity = iota + 1 // the torrent cannot be used as is
+	SeverityWarning                     // the torrent works but is unusual or unportable
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// Problem is one issue found by Validate.
+type Problem struct {
+	Severity Severity
+	Field    string // key path of the value, e.g. info.files[3].path
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Field, p.Message)
+}
+
+// Problems is the list of issues found by Validate.
+type Problems []Problem
+
+// Errors returns the problems of error severity.
+func (p Problems) Errors() Problems {
+	return p.filter(SeverityError)
+}
+
+// Warnings returns the problems of warning severity.
+func (p Problems) Warnings() Problems {
+	return p.filter(SeverityWarning)
+}
+
+func (p Problems) filter(severity Severity) Problems {
+	var filtered Problems
+	for _, problem := range p {
+		if problem.Severity == severity {
+			filtered = append(filtered, problem)
+		}
+	}
+	return filtered
+}
+
+// Err returns an error listing the errors, or nil if there are none.
+func (p Problems) Err() error {
+	errs := p.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, len(errs))
+	for i, problem := range errs {
+		messages[i] = problem.Field + ": " + problem.Message
+	}
+	return errors.New("invalid torrent: " + strings.Join(messages, "; "))
+}
+
+// validator collects problems as Validate goes.
+type validator struct {
+	problems Problems
+}
+
+func (v *validator) errorf(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Severity: SeverityError, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Severity: SeverityWarning, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the torrent for every problem it can find, rather than
+// stopping at the first. A torrent with no problems of error severity can
+// be downloaded; warnings point out things that may surprise other clients
+// or operating systems.
+func (t *TorrentFile) Validate() Problems {
+	v := new(validator)
+	t.validateTrackers(v)
+	if t.CreationDate < 0 || t.CreationDate > time.Now().Add(24*time.Hour).Unix() {
+		v.warnf("creation date", "%d is not a plausible time", t.CreationDate)
+	}
+
+	info := &t.Info
+	keys := t.loadedKeys()
+	switch {
+	case keys != nil && keys.PieceLength == nil:
+		v.errorf("info.piece length", "is missing")
+	case info.PieceLength <= 0:
+		v.errorf("info.piece length", "must be positive, not %d", info.PieceLength)
+	case info.PieceLength&(info.PieceLength-1) != 0:
+		v.warnf("info.piece length", "%d is not a power of two", info.PieceLength)
+	}
+	switch {
+	case keys != nil && keys.Name == nil:
+		v.errorf("info.name", "is missing")
+	case info.Name == "":
+		v.errorf("info.name", "is empty")
+	default:
+		v.checkPathElement("info.name", info.Name)
+	}
+	if info.NameUTF8 != "" {
+		v.checkUTF8Path("info.name.utf-8", []string{info.NameUTF8})
+	}
+	if info.Private != nil && *info.Private != 0 && *info.Private != 1 {
+		v.warnf("info.private", "%d is neither 0 nor 1, so the torrent is not private", *info.Private)
+	}
+	if info.IsPrivate() && len(t.Nodes) > 0 {
+		v.warnf("nodes", "private torrents do not use DHT, so the nodes are ignored")
+	}
+
+	version := info.Version()
+	if info.MetaVersion != 0 && info.MetaVersion != 2 {
+		v.errorf("info.meta version", "unsupported version %d", info.MetaVersion)
+	}
+	if version != V2 {
+		t.validateV1(v, keys)
+	}
+	if version != V1 {
+		t.validateV2(v)
+	}
+	if version == Hybrid {
+		t.validateAlignment(v)
+	}
+	return v.problems
+}
+
+func (t *TorrentFile) validateTrackers(v *validator) {
+	if t.Announce == "" && len(t.AnnounceList) == 0 && len(t.URLList) == 0 {
+		v.warnf("announce", "no trackers, peers can only be found through DHT or PEX")
+	}
+	check := func(field, tracker string) {
+		u, err := url.Parse(tracker)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			v.warnf(field, "%q is not a URL", tracker)
+		}
+	}
+	if t.Announce != "" {
+		check("announce", t.Announce)
+	}
+	for i, tier := range t.AnnounceList {
+		for j, tracker := range tier {
+			check(fmt.Sprintf("announce-list[%d][%d]", i, j), tracker)
+		}
+	}
+	for i, seed := range t.URLList {
+		check(fmt.Sprintf("url-list[%d]", i), seed)
+	}
+}
+
+// loadedKeys returns the keys Validate needs to tell apart from zero values
+// as they were in the loaded info dict, or nil for a torrent built in code.
+func (t *TorrentFile) loadedKeys() *infoKeys {
+	if t.rawInfo == nil {
+		return nil
+	}
+	keys := new(infoKeys)
+	if err := bencoder.NewDecoder(bytes.NewReader(t.rawInfo)).Decode(keys); err != nil {
+		return nil
+	}
+	return keys
+}
+
+// infoKeys holds the encoding of the info keys that decode to a zero value
+// when missing, so Validate can report them as missing.
+type infoKeys struct {
+	PieceLength bencoder.RawMessage `bencode:"piece length"`
+	Name        bencoder.RawMessage `bencode:"name"`
+	Files       []struct {
+		Length bencoder.RawMessage `bencode:"length"`
+		Path   bencoder.RawMessage `bencode:"path"`
+	} `bencode:"files"`
+}
+
+func (t *TorrentFile) validateV1(v *validator, keys *infoKeys) {
+	info := &t.Info
+	if info.Length > 0 && len(info.Files) > 0 {
+		v.errorf("info", "has both length and files")
+	}
+	if info.Length < 0 {
+		v.errorf("info.length", "is negative")
+	}
+	if len(info.Files) == 0 && info.Length == 0 {
+		v.warnf("info.length", "the torrent has no content")
+	}
+
+	total := info.Length
+	seen := map[string]int{}
+	for i, file := range info.Files {
+		field := fmt.Sprintf("info.files[%d]", i)
+		loaded := keys != nil && i < len(keys.Files)
+		if loaded && keys.Files[i].Length == nil {
+			v.errorf(field+".length", "is missing")
+		} else if file.Length < 0 {
+			v.errorf(field+".length", "is negative")
+		}
+		total += file.Length
+		if loaded && keys.Files[i].Path == nil {
+			v.errorf(field+".path", "is missing")
+			continue
+		}
+		if len(file.Path) == 0 {
+			v.errorf(field+".path", "is empty")
+			continue
+		}
+		for j, element := range file.Path {
+			v.checkPathElement(fmt.Sprintf("%s.path[%d]", field, j), element)
+		}
+		if len(file.PathUTF8) > 0 {
+			v.checkUTF8Path(field+".path.utf-8", file.PathUTF8)
+		}
+		if file.IsSymlink() {
+			if len(file.SymlinkPath) == 0 {
+				v.errorf(field+".symlink path", "is missing for a symlink")
+			}
+			for j, element := range file.SymlinkPath {
+				v.checkPathElement(fmt.Sprintf("%s.symlink path[%d]", field, j), element)
+			}
+		}
+		if file.IsPadding() {
+			// padding files of the same length may share a name
+			continue
+		}
+		key := strings.Join(file.Path, "/")
+		if first, ok := seen[key]; ok {
+			v.errorf(field+".path", "%q is also the path of file %d", key, first)
+		}
+		seen[key] = i
+	}
+
+	if len(info.Pieces)%sha1.Size != 0 {
+		v.errorf("info.pieces", "%d bytes is not a whole number of hashes", len(info.Pieces))
+	} else if info.PieceLength > 0 {
+		want := (total + info.PieceLength - 1) / info.PieceLength
+		if got := int64(len(info.Pieces) / sha1.Size); got != want {
+			v.errorf("info.pieces", "has %d hashes, but %d bytes in pieces of %d need %d", got, total, info.PieceLength, want)
+		}
+	}
+}
+
+func (t *TorrentFile) validateV2(v *validator) {
+	if len(t.Info.FileTree) == 0 {
+		v.errorf("info.file tree", "is empty")
+		return
+	}
+	for _, file := range t.Info.FileTree.Files() {
+		field := "info.file tree." + strings.Join(file.Path, ".")
+		for _, element := range file.Path {
+			v.checkPathElement(field, element)
+		}
+		if file.Length < 0 {
+			v.errorf(field, "has a negative length")
+		}
+	}
+	if err := t.CheckPieceLayers(); err != nil {
+		v.errorf("piece layers", "%v", err)
+	}
+}
+
+// validateAlignment checks that the v1 files of a hybrid torrent start on
+// piece boundaries, padded as needed, as the v2 files do.
+func (t *TorrentFile) validateAlignment(v *validator) {
+	if t.Info.PieceLength <= 0 {
+		return
+	}
+	var offset int64
+	for i, file := range t.Info.Files {
+		if !file.IsPadding() && file.Length > 0 && offset%t.Info.PieceLength != 0 {
+			v.errorf(fmt.Sprintf("info.files[%d]", i), "does not start on a piece boundary, as the v2 file tree needs")
+		}
+		offset += file.Length
+	}
+}
+
+// checkUTF8Path checks a name.utf-8 or path.utf-8, which readers use in
+// preference to the plain name or path when it is valid.
+func (v *validator) checkUTF8Path(field string, path []string) {
+	for i, element := range path {
+		if !utf8.ValidString(element) {
+			v.warnf(field, "is not valid UTF-8, so it is ignored")
+			return
+		}
+		if len(path) > 1 {
+			v.checkPathElement(fmt.Sprintf("%s[%d]", field, i), element)
+		} else {
+			v.checkPathElement(field, element)
+		}
+	}
+}
+
+// checkPathElement reports an element of a file path that could escape the
+// download directory, as errors, or is not portable, as warnings.
+func (v *validator) checkPathElement(field, element string) {
+	switch {
+	case element == "":
+		v.errorf(field, "empty path element")
+	case element == "." || element == "..":
+		v.errorf(field, "path element %q refers to a directory outside the file's own", element)
+	case strings.ContainsAny(element, "/\\"):
+		v.errorf(field, "path element %q contains a path separator", element)
+	case strings.ContainsRune(element, 0):
+		v.errorf(field, "path element %q contains a NUL byte", element)
+	case isReservedName(element):
+		v.warnf(field, "%q is a reserved file name on Windows", element)
+	case strings.ContainsAny(element, windowsInvalidChars) || hasControlChars(element):
+		v.warnf(field, "%q contains characters not allowed in Windows file names", element)
+	case strings.HasSuffix(element, ".") || strings.HasSuffix(element, " "):
+		v.warnf(field, "%q ends with a dot or space, which Windows drops", element)
+	}
+}
+
+const windowsInvalidChars = `<>:"|?*`
+
+// reservedNames are the device names Windows reserves in every directory,
+// with or without an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+func isReservedName(element string) bool {
+	base, _, _ := strings.Cut(element, ".")
+	return reservedNames[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+func hasControlChars(element string) bool {
+	for _, r := range element {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizePath makes the path of a file in a torrent safe to create on any
+// operating system. Separators, NUL and other characters Windows rejects
+// become '_', trailing dots and spaces are dropped, so that "." and ".."
+// vanish, and reserved names such as CON get a leading '_'. Elements left
+// empty are removed.
+func SanitizePath(path []string) []string {
+	var safe []string
+	for _, element := range path {
+		element = strings.Map(func(r rune) rune {
+			if r < 0x20 || r == 0x7f || strings.ContainsRune("/\\"+windowsInvalidChars, r) {
+				return '_'
+			}
+			return r
+		}, element)
+		element = strings.TrimRight(element, ". ")
+		if element == "" {
+			continue
+		}
+		if isReservedName(element) {
+			element = "_" + element
+		}
+		safe = append(safe, element)
+	}
+	return safe
+}
+
+// SafeJoin returns the location under dir to store the file at path,
+// sanitised with SanitizePath. It fails if nothing of the path is left.
+func SafeJoin(dir string, path []string) (string, error) {
+	safe := SanitizePath(path)
+	if len(safe) == 0 {
+		return "", fmt.Errorf("file path %q has no usable elements", path)
+	}
+	joined := filepath.Join(append([]string{dir}, safe...)...)
+	// sanitised elements cannot climb out of dir, but make sure
+	relative, err := filepath.Rel(dir, joined)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file path %q leaves the download directory", path)
+	}
+	return joined, nil
+}
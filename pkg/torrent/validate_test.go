@@ -0,0 +1,306 @@
+package torrent
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func validTorrent() *TorrentFile {
+	return &TorrentFile{
+		Announce: "http://tracker.example.com/announce",
+		Info: InfoDict{
+			PieceLength: 16,
+			Pieces:      make([]byte, 3*20),
+			Name:        "dir",
+			Files: []File{
+				{Length: 20, Path: []string{"a"}},
+				{Length: 20, Path: []string{"sub", "b"}},
+			},
+		},
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	if problems := validTorrent().Validate(); problems != nil {
+		t.Errorf("Validate() = %v, expected no problems", problems)
+	}
+
+	torrent, err := NewTorrentFromFile("./testdata/sub_zip.py.torrent")
+	if err != nil {
+		t.Fatalf("Failed to read torrent file: %v", err)
+	}
+	if problems := torrent.Validate(); problems != nil {
+		t.Errorf("Validate() = %v, expected no problems", problems)
+	}
+
+	v2 := newV2Torrent(t, BlockSize, map[string][]byte{"a": testContent(3*BlockSize, 1)})
+	if problems := v2.Validate(); problems != nil {
+		t.Errorf("Validate() of a v2 torrent = %v, expected no problems", problems)
+	}
+}
+
+func TestValidate_Problems(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(torrent *TorrentFile)
+		want   []string
+	}{
+		{
+			name:   "zero piece length",
+			modify: func(torrent *TorrentFile) { torrent.Info.PieceLength = 0 },
+			want:   []string{"error: info.piece length: must be positive, not 0"},
+		},
+		{
+			name:   "wrong piece count",
+			modify: func(torrent *TorrentFile) { torrent.Info.Pieces = make([]byte, 2*20) },
+			want:   []string{"error: info.pieces: has 2 hashes, but 40 bytes in pieces of 16 need 3"},
+		},
+		{
+			name:   "partial hash",
+			modify: func(torrent *TorrentFile) { torrent.Info.Pieces = make([]byte, 50) },
+			want:   []string{"error: info.pieces: 50 bytes is not a whole number of hashes"},
+		},
+		{
+			name:   "length and files",
+			modify: func(torrent *TorrentFile) { torrent.Info.Length = 8 },
+			want:   []string{"error: info: has both length and files"},
+		},
+		{
+			name: "unsafe paths",
+			modify: func(torrent *TorrentFile) {
+				torrent.Info.Files[0].Path = []string{"..", "etc", "passwd"}
+				torrent.Info.Files[1].Path = []string{"/abs"}
+			},
+			want: []string{
+				`error: info.files[0].path[0]: path element ".." refers to a directory outside the file's own`,
+				`error: info.files[1].path[0]: path element "/abs" contains a path separator`,
+			},
+		},
+		{
+			name: "unportable names",
+			modify: func(torrent *TorrentFile) {
+				torrent.Info.Files[0].Path = []string{"con.txt"}
+				torrent.Info.Files[1].Path = []string{"what?", "dot."}
+			},
+			want: []string{
+				`warning: info.files[0].path[0]: "con.txt" is a reserved file name on Windows`,
+				`warning: info.files[1].path[0]: "what?" contains characters not allowed in Windows file names`,
+				`warning: info.files[1].path[1]: "dot." ends with a dot or space, which Windows drops`,
+			},
+		},
+		{
+			name: "duplicate paths",
+			modify: func(torrent *TorrentFile) {
+				torrent.Info.Files[1].Path = []string{"a"}
+			},
+			want: []string{`error: info.files[1].path: "a" is also the path of file 0`},
+		},
+		{
+			name: "trackers and name",
+			modify: func(torrent *TorrentFile) {
+				torrent.Announce = ""
+				torrent.Info.Name = ""
+			},
+			want: []string{
+				"warning: announce: no trackers, peers can only be found through DHT or PEX",
+				"error: info.name: is empty",
+			},
+		},
+		{
+			name: "odd piece length and bad tracker",
+			modify: func(torrent *TorrentFile) {
+				torrent.Announce = "not a url"
+				torrent.Info.PieceLength = 20
+				torrent.Info.Pieces = make([]byte, 2*20)
+			},
+			want: []string{
+				`warning: announce: "not a url" is not a URL`,
+				"warning: info.piece length: 20 is not a power of two",
+			},
+		},
+		{
+			name: "utf-8 variants",
+			modify: func(torrent *TorrentFile) {
+				torrent.Info.NameUTF8 = "bad\xff"
+				torrent.Info.Files[1].PathUTF8 = []string{"sub", ".."}
+			},
+			want: []string{
+				"warning: info.name.utf-8: is not valid UTF-8, so it is ignored",
+				`error: info.files[1].path.utf-8[1]: path element ".." refers to a directory outside the file's own`,
+			},
+		},
+		{
+			name: "nodes of a private torrent",
+			modify: func(torrent *TorrentFile) {
+				private := int64(1)
+				torrent.Info.Private = &private
+				torrent.Nodes = []Node{{Host: "router.example.com", Port: 6881}}
+				torrent.URLList = URLList{"seed"}
+			},
+			want: []string{
+				`warning: url-list[0]: "seed" is not a URL`,
+				"warning: nodes: private torrents do not use DHT, so the nodes are ignored",
+			},
+		},
+		{
+			name: "unusual private value",
+			modify: func(torrent *TorrentFile) {
+				private := int64(2)
+				torrent.Info.Private = &private
+			},
+			want: []string{"warning: info.private: 2 is neither 0 nor 1, so the torrent is not private"},
+		},
+		{
+			name: "symlinks and padding",
+			modify: func(torrent *TorrentFile) {
+				torrent.Info.Files = []File{
+					{Length: 20, Path: []string{"a"}},
+					paddingFile(12),
+					{Length: 8, Path: []string{"b"}},
+					paddingFile(12),
+					{Length: 0, Path: []string{"link"}, Attr: "l"},
+					{Length: 0, Path: []string{"up"}, Attr: "l", SymlinkPath: []string{"..", "x"}},
+				}
+				torrent.Info.Pieces = make([]byte, 4*20)
+			},
+			want: []string{
+				"error: info.files[4].symlink path: is missing for a symlink",
+				`error: info.files[5].symlink path[0]: path element ".." refers to a directory outside the file's own`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := validTorrent()
+			tt.modify(torrent)
+			var got []string
+			for _, problem := range torrent.Validate() {
+				got = append(got, problem.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_MissingKeys(t *testing.T) {
+	// a torrent missing required keys still loads, so Validate can report them
+	data := []byte("d8:announce15:http://tracker/4:infod5:filesld4:pathl1:aeed6:lengthi0eee6:pieces0:ee")
+	torrent, err := NewTorrentFromBencode(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var got []string
+	for _, problem := range torrent.Validate().Errors() {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		"error: info.piece length: is missing",
+		"error: info.name: is missing",
+		"error: info.files[0].length: is missing",
+		"error: info.files[1].path: is missing",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate().Errors() = %q, expected %q", got, want)
+	}
+
+	// built in code, the same zero values are reported as such
+	torrent = &TorrentFile{Info: torrent.Info}
+	got = nil
+	for _, problem := range torrent.Validate().Errors() {
+		got = append(got, problem.String())
+	}
+	want = []string{
+		"error: info.piece length: must be positive, not 0",
+		"error: info.name: is empty",
+		"error: info.files[1].path: is empty",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate().Errors() = %q, expected %q", got, want)
+	}
+}
+
+func TestValidate_V2Problems(t *testing.T) {
+	torrent := newV2Torrent(t, BlockSize, map[string][]byte{"a": testContent(3*BlockSize, 1)})
+	torrent.PieceLayers = nil
+	torrent.Info.MetaVersion = 3
+	problems := torrent.Validate()
+	if len(problems.Errors()) != 1 || !strings.Contains(problems.Err().Error(), "unsupported version 3") {
+		t.Errorf("Validate() = %v", problems)
+	}
+
+	torrent.Info.MetaVersion = 2
+	if err := torrent.Validate().Err(); err == nil || !strings.Contains(err.Error(), "piece layers") {
+		t.Errorf("Validate().Err() = %v, expected a piece layers error", err)
+	}
+}
+
+func TestValidate_HybridAlignment(t *testing.T) {
+	torrent := newV2Torrent(t, BlockSize, map[string][]byte{
+		"a": testContent(BlockSize+1, 1),
+		"b": testContent(100, 2),
+	})
+	torrent.Info.Files = []File{
+		{Length: BlockSize + 1, Path: []string{"dir", "a"}},
+		paddingFile(BlockSize - 1),
+		{Length: 100, Path: []string{"dir", "b"}},
+	}
+	torrent.Info.Pieces = make([]byte, 3*20)
+	if problems := torrent.Validate(); problems != nil {
+		t.Errorf("Validate() of an aligned hybrid = %v, expected no problems", problems)
+	}
+
+	torrent.Info.Files = append(torrent.Info.Files[:1], torrent.Info.Files[2])
+	torrent.Info.Pieces = make([]byte, 2*20)
+	want := "info.files[1]: does not start on a piece boundary, as the v2 file tree needs"
+	if err := torrent.Validate().Err(); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Validate().Err() = %v, expected %q", err, want)
+	}
+}
+
+func TestProblems_Filter(t *testing.T) {
+	torrent := validTorrent()
+	torrent.Announce = ""
+	torrent.Info.PieceLength = 0
+	problems := torrent.Validate()
+	if len(problems.Errors()) != 1 || len(problems.Warnings()) != 1 {
+		t.Errorf("Errors() = %v, Warnings() = %v", problems.Errors(), problems.Warnings())
+	}
+	if problems.Warnings().Err() != nil {
+		t.Errorf("Err() of warnings only = %v, expected nil", problems.Warnings().Err())
+	}
+}
+
+func TestSanitizePath(t *testing.T) {
+	tests := []struct {
+		path []string
+		want []string
+	}{
+		{[]string{"dir", "file.txt"}, []string{"dir", "file.txt"}},
+		{[]string{"..", "..", "etc", "passwd"}, []string{"etc", "passwd"}},
+		{[]string{".", "", "a"}, []string{"a"}},
+		{[]string{"/abs", `C:\x`}, []string{"_abs", "C__x"}},
+		{[]string{"CON", "lpt1.txt", "console"}, []string{"_CON", "_lpt1.txt", "console"}},
+		{[]string{"trailing. ", "nul\x00byte", "q?"}, []string{"trailing", "nul_byte", "q_"}},
+		{[]string{"..."}, nil},
+	}
+	for _, tt := range tests {
+		if got := SanitizePath(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SanitizePath(%q) = %q, expected %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	dir := filepath.FromSlash("/downloads")
+	got, err := SafeJoin(dir, []string{"..", "x", "..", "y"})
+	if err != nil || got != filepath.Join(dir, "x", "y") {
+		t.Errorf("SafeJoin() = %q, %v", got, err)
+	}
+	if _, err := SafeJoin(dir, []string{"..", "."}); err == nil {
+		t.Errorf("expected an error for a path with nothing left")
+	}
+}
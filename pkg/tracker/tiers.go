@@ -0,0 +1,126 @@
+// Package tracker chooses which of a torrent's trackers to announce to.
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+	"torrent/pkg/torrent"
+)
+
+// Tiers holds the trackers of a torrent in tiers, as BEP 12 describes. The
+// trackers of each tier are shuffled once, when the Tiers is made; after
+// that they are tried in order, tier by tier, and a tracker that answers
+// moves to the front of its tier so it is tried first next time.
+type Tiers struct {
+	mu    sync.Mutex
+	tiers [][]string
+}
+
+// NewTiers returns the tiers of the torrent's announce-list, or a single
+// tier of its announce when the list is absent or empty. The tiers are
+// shuffled with rng, or a randomly seeded source when rng is nil.
+func NewTiers(t *torrent.TorrentFile, rng *rand.Rand) *Tiers {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	var tiers [][]string
+	for _, tier := range t.AnnounceList {
+		var trackers []string
+		for _, tracker := range tier {
+			if tracker != "" {
+				trackers = append(trackers, tracker)
+			}
+		}
+		if len(trackers) == 0 {
+			continue
+		}
+		rng.Shuffle(len(trackers), func(i, j int) {
+			trackers[i], trackers[j] = trackers[j], trackers[i]
+		})
+		tiers = append(tiers, trackers)
+	}
+	if len(tiers) == 0 && t.Announce != "" {
+		tiers = [][]string{{t.Announce}}
+	}
+	return &Tiers{tiers: tiers}
+}
+
+// Tiers returns a copy of the tiers in their current order.
+func (t *Tiers) Tiers() [][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tiers := make([][]string, len(t.tiers))
+	for i, tier := range t.tiers {
+		tiers[i] = append([]string(nil), tier...)
+	}
+	return tiers
+}
+
+// Trackers returns every tracker in the order they would be tried.
+func (t *Tiers) Trackers() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var trackers []string
+	for _, tier := range t.tiers {
+		trackers = append(trackers, tier...)
+	}
+	return trackers
+}
+
+// Len returns the number of trackers.
+func (t *Tiers) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, tier := range t.tiers {
+		n += len(tier)
+	}
+	return n
+}
+
+// Promote moves tracker to the front of its tier, shifting the trackers
+// before it back by one. It does nothing for an unknown tracker.
+func (t *Tiers) Promote(tracker string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tier := range t.tiers {
+		for i, url := range tier {
+			if url == tracker {
+				copy(tier[1:i+1], tier[:i])
+				tier[0] = tracker
+				return
+			}
+		}
+	}
+}
+
+// ErrNoTrackers is returned by Announce for a torrent without trackers.
+var ErrNoTrackers = errors.New("no trackers")
+
+// Announce calls announce for each tracker in order until one succeeds,
+// promotes that tracker and returns it. If every tracker fails, the error
+// of the last is returned. It stops early, with the context's error, when
+// ctx is done.
+func (t *Tiers) Announce(ctx context.Context, announce func(ctx context.Context, tracker string) error) (string, error) {
+	trackers := t.Trackers()
+	if len(trackers) == 0 {
+		return "", ErrNoTrackers
+	}
+	var last error
+	for _, tracker := range trackers {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := announce(ctx, tracker); err != nil {
+			last = fmt.Errorf("%s: %w", tracker, err)
+			continue
+		}
+		t.Promote(tracker)
+		return tracker, nil
+	}
+	return "", fmt.Errorf("all %d trackers failed: %w", len(trackers), last)
+}
@@ -0,0 +1,151 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"torrent/pkg/torrent"
+)
+
+func newTiers(announce string, list [][]string) *Tiers {
+	t := &torrent.TorrentFile{Announce: announce, AnnounceList: list}
+	return NewTiers(t, rand.New(rand.NewSource(1)))
+}
+
+func TestNewTiers(t *testing.T) {
+	list := [][]string{
+		{"http://a1/", "http://a2/", "http://a3/", "http://a4/"},
+		{},
+		{"http://b1/", "", "http://b2/"},
+	}
+	tiers := newTiers("http://announce/", list)
+
+	got := tiers.Tiers()
+	if len(got) != 2 {
+		t.Fatalf("Tiers() = %q, expected 2 tiers", got)
+	}
+	for i, want := range [][]string{list[0], {"http://b1/", "http://b2/"}} {
+		shuffled := append([]string(nil), got[i]...)
+		sort.Strings(shuffled)
+		if !reflect.DeepEqual(shuffled, want) {
+			t.Errorf("tier %d = %q, expected a shuffle of %q", i, got[i], want)
+		}
+	}
+	if tiers.Len() != 6 {
+		t.Errorf("Len() = %d, expected 6", tiers.Len())
+	}
+
+	// seed 1 shuffles both tiers
+	want := [][]string{{"http://a1/", "http://a2/", "http://a4/", "http://a3/"}, {"http://b2/", "http://b1/"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tiers() = %q, expected %q", got, want)
+	}
+
+	// the same seed gives the same order, and the torrent is left alone
+	if again := newTiers("http://announce/", list).Tiers(); !reflect.DeepEqual(again, got) {
+		t.Errorf("Tiers() with the same seed = %q, expected %q", again, got)
+	}
+	if list[0][0] != "http://a1/" || list[0][3] != "http://a4/" {
+		t.Errorf("the announce-list was shuffled in place: %q", list[0])
+	}
+}
+
+func TestNewTiers_FallsBackToAnnounce(t *testing.T) {
+	tests := []struct {
+		name string
+		list [][]string
+	}{
+		{"no list", nil},
+		{"empty tiers", [][]string{{}, {""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTiers("http://announce/", tt.list).Tiers(); !reflect.DeepEqual(got, [][]string{{"http://announce/"}}) {
+				t.Errorf("Tiers() = %q", got)
+			}
+		})
+	}
+	if got := newTiers("", nil).Tiers(); len(got) != 0 {
+		t.Errorf("Tiers() without trackers = %q", got)
+	}
+}
+
+func TestTiers_Promote(t *testing.T) {
+	tiers := &Tiers{tiers: [][]string{{"a", "b", "c", "d"}, {"e", "f"}}}
+	tiers.Promote("c")
+	tiers.Promote("f")
+	tiers.Promote("unknown")
+	want := [][]string{{"c", "a", "b", "d"}, {"f", "e"}}
+	if got := tiers.Tiers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tiers() = %q, expected %q", got, want)
+	}
+}
+
+func TestTiers_Announce(t *testing.T) {
+	tiers := &Tiers{tiers: [][]string{{"a1", "a2", "a3"}, {"b1", "b2"}}}
+	up := map[string]bool{"a3": true, "b2": true}
+	var tried []string
+	announce := func(ctx context.Context, tracker string) error {
+		tried = append(tried, tracker)
+		if !up[tracker] {
+			return errors.New("timed out")
+		}
+		return nil
+	}
+
+	got, err := tiers.Announce(context.Background(), announce)
+	if err != nil || got != "a3" {
+		t.Fatalf("Announce() = %q, %v, expected a3", got, err)
+	}
+	if want := []string{"a1", "a2", "a3"}; !reflect.DeepEqual(tried, want) {
+		t.Errorf("tried %q, expected %q", tried, want)
+	}
+
+	// the tracker that answered is tried first next time
+	tried = nil
+	if got, err := tiers.Announce(context.Background(), announce); err != nil || got != "a3" || len(tried) != 1 {
+		t.Errorf("Announce() = %q, %v after trying %q", got, err, tried)
+	}
+
+	// a whole tier down falls through to the next
+	up["a3"] = false
+	tried = nil
+	if got, err := tiers.Announce(context.Background(), announce); err != nil || got != "b2" {
+		t.Errorf("Announce() = %q, %v, expected b2", got, err)
+	}
+	if want := []string{"a3", "a1", "a2", "b1", "b2"}; !reflect.DeepEqual(tried, want) {
+		t.Errorf("tried %q, expected %q", tried, want)
+	}
+	if want := [][]string{{"a3", "a1", "a2"}, {"b2", "b1"}}; !reflect.DeepEqual(tiers.Tiers(), want) {
+		t.Errorf("Tiers() = %q, expected %q", tiers.Tiers(), want)
+	}
+}
+
+func TestTiers_AnnounceErrors(t *testing.T) {
+	failure := errors.New("connection refused")
+	tiers := &Tiers{tiers: [][]string{{"a"}, {"b"}}}
+	_, err := tiers.Announce(context.Background(), func(ctx context.Context, tracker string) error {
+		return failure
+	})
+	if !errors.Is(err, failure) || err.Error() != "all 2 trackers failed: b: connection refused" {
+		t.Errorf("Announce() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err = tiers.Announce(ctx, func(ctx context.Context, tracker string) error {
+		calls++
+		cancel()
+		return failure
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("Announce() after cancel = %v with %d calls", err, calls)
+	}
+
+	if _, err := new(Tiers).Announce(context.Background(), nil); !errors.Is(err, ErrNoTrackers) {
+		t.Errorf("Announce() without trackers error = %v", err)
+	}
+}